@@ -0,0 +1,201 @@
+package clustering
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"inshorts-news-api/models"
+	"inshorts-news-api/repositories"
+)
+
+type Options struct {
+	// Threshold is the estimated word overlap (Jaccard similarity) from
+	// which two articles count as the same story
+	Threshold float64
+	// Window is how far apart in publication time two articles of the same
+	// story may be
+	Window time.Duration
+}
+
+// Clusterer groups near-duplicate articles into stories. Each article's
+// story_id is the ID of the earliest article of its story, so a story ID
+// also identifies the article that started it.
+type Clusterer struct {
+	store   repositories.ArticleStore
+	options Options
+}
+
+func NewClusterer(store repositories.ArticleStore, options Options) *Clusterer {
+	if options.Threshold <= 0 {
+		options.Threshold = 0.5
+	}
+	if options.Window <= 0 {
+		options.Window = 48 * time.Hour
+	}
+
+	return &Clusterer{store: store, options: options}
+}
+
+// Assign sets the StoryID of articles that are about to be stored, matching
+// them against stored articles from around the same time and each other.
+// Stored copies of the articles themselves are ignored, so an edited
+// article is matched on its new content.
+func (c *Clusterer) Assign(ctx context.Context, articles []models.Article) error {
+	if len(articles) == 0 {
+		return nil
+	}
+
+	assigning := make(map[string]bool, len(articles))
+	from, to := articles[0].PublicationDate, articles[0].PublicationDate
+	for _, article := range articles {
+		assigning[article.ID] = true
+		if article.PublicationDate.Before(from) {
+			from = article.PublicationDate
+		}
+		if article.PublicationDate.After(to) {
+			to = article.PublicationDate
+		}
+	}
+
+	stored, err := c.store.ListPublishedBetween(ctx, from.Add(-c.options.Window), to.Add(c.options.Window))
+	if err != nil {
+		return err
+	}
+
+	idx := newIndex(c.options)
+	for _, article := range stored {
+		if article.StoryID != "" && !assigning[article.ID] {
+			idx.add(article, article.StoryID)
+		}
+	}
+
+	for _, i := range byPublicationDate(articles) {
+		articles[i].StoryID = idx.assign(articles[i])
+	}
+	return nil
+}
+
+// Rebuild clusters every stored article from scratch and saves the story
+// IDs that changed. It returns how many articles moved to another story.
+func (c *Clusterer) Rebuild(ctx context.Context, batchSize int) (int, error) {
+	var all []models.Article
+	afterID := ""
+	for {
+		batch, err := c.store.ListAfterID(ctx, afterID, batchSize)
+		if err != nil {
+			return 0, err
+		}
+		if len(batch) == 0 {
+			break
+		}
+		all = append(all, batch...)
+		afterID = batch[len(batch)-1].ID
+	}
+
+	idx := newIndex(c.options)
+	changed := make(map[string]string)
+	for _, i := range byPublicationDate(all) {
+		if storyID := idx.assign(all[i]); storyID != all[i].StoryID {
+			changed[all[i].ID] = storyID
+		}
+	}
+
+	if err := c.store.SetStoryIDs(ctx, changed); err != nil {
+		return 0, err
+	}
+	return len(changed), nil
+}
+
+// byPublicationDate returns the indexes of articles from oldest to newest,
+// so stories are named after their first article
+func byPublicationDate(articles []models.Article) []int {
+	order := make([]int, len(articles))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		x, y := articles[order[a]], articles[order[b]]
+		if !x.PublicationDate.Equal(y.PublicationDate) {
+			return x.PublicationDate.Before(y.PublicationDate)
+		}
+		return x.ID < y.ID
+	})
+	return order
+}
+
+type indexedArticle struct {
+	signature Signature
+	published time.Time
+	storyID   string
+}
+
+// index finds earlier articles similar to a new one through the bands of
+// their signatures instead of comparing against every article
+type index struct {
+	options  Options
+	articles []indexedArticle
+	bands    map[bandKey][]int
+}
+
+func newIndex(options Options) *index {
+	return &index{
+		options: options,
+		bands:   make(map[bandKey][]int),
+	}
+}
+
+func (idx *index) add(article models.Article, storyID string) {
+	sig, ok := NewSignature(article.Title, article.Description)
+	if !ok {
+		return
+	}
+	idx.insert(indexedArticle{signature: sig, published: article.PublicationDate, storyID: storyID})
+}
+
+func (idx *index) insert(entry indexedArticle) {
+	idx.articles = append(idx.articles, entry)
+	n := len(idx.articles) - 1
+	for _, key := range entry.signature.bandKeys() {
+		idx.bands[key] = append(idx.bands[key], n)
+	}
+}
+
+// assign returns the story of the most similar indexed article, or starts a
+// new story named after the article, and indexes the article
+func (idx *index) assign(article models.Article) string {
+	sig, ok := NewSignature(article.Title, article.Description)
+	if !ok {
+		return article.ID
+	}
+
+	storyID := article.ID
+	best := idx.options.Threshold
+	compared := make(map[int]bool)
+
+	for _, key := range sig.bandKeys() {
+		for _, n := range idx.bands[key] {
+			if compared[n] {
+				continue
+			}
+			compared[n] = true
+
+			candidate := idx.articles[n]
+			gap := article.PublicationDate.Sub(candidate.published)
+			if gap < 0 {
+				gap = -gap
+			}
+			if gap > idx.options.Window {
+				continue
+			}
+
+			if similarity := Similarity(sig, candidate.signature); similarity >= best {
+				best = similarity
+				storyID = candidate.storyID
+			}
+		}
+	}
+
+	idx.insert(indexedArticle{signature: sig, published: article.PublicationDate, storyID: storyID})
+	return storyID
+}
@@ -0,0 +1,86 @@
+package clustering_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"inshorts-news-api/clustering"
+	"inshorts-news-api/models"
+	"inshorts-news-api/repositories"
+)
+
+func newArticle(id, title, description string, published time.Time) models.Article {
+	return models.Article{
+		ID:              id,
+		Title:           title,
+		Description:     description,
+		URL:             "https://example.com/" + id,
+		PublicationDate: published,
+	}
+}
+
+func TestSimilarityEstimatesWordOverlap(t *testing.T) {
+	a, _ := clustering.NewSignature("Poop dumped inside house of YouTuber Savukku", "Property ransacked in Chennai")
+	b, _ := clustering.NewSignature("Poop dumped inside YouTuber Savukku's house", "Property ransacked in Chennai")
+	c, _ := clustering.NewSignature("RCB to bowl first against KKR", "Playing XIs announced")
+
+	if s := clustering.Similarity(a, b); s < 0.6 {
+		t.Errorf("expected rewrites of one story to be similar, got %.2f", s)
+	}
+	if s := clustering.Similarity(a, c); s > 0.2 {
+		t.Errorf("expected unrelated articles to differ, got %.2f", s)
+	}
+	if _, ok := clustering.NewSignature("", "a"); ok {
+		t.Error("expected no signature for text without words")
+	}
+}
+
+func TestClustererGroupsNearDuplicates(t *testing.T) {
+	ctx := context.Background()
+	base := time.Date(2025, 3, 26, 8, 0, 0, 0, time.UTC)
+
+	store := repositories.NewMemoryArticleStore()
+	err := store.BulkCreate(ctx, []models.Article{
+		newArticle("b-first", "Man kidnaps tenant over affair with wife, buries him alive in Haryana",
+			"The man buried the tenant alive in a pit, police said", base),
+		newArticle("a-later", "Haryana man discovers wife's affair with tenant, buries him alive",
+			"Police said the tenant was buried alive in a pit", base.Add(3*time.Hour)),
+		newArticle("stale", "Haryana man discovers wife's affair with tenant, buries him alive",
+			"Police said the tenant was buried alive in a pit", base.Add(-30*24*time.Hour)),
+		newArticle("other", "Sensex closes above 78,000 as banks rally", "Markets ended higher", base),
+	})
+	if err != nil {
+		t.Fatalf("seeding articles: %v", err)
+	}
+
+	clusterer := clustering.NewClusterer(store, clustering.Options{})
+	if _, err := clusterer.Rebuild(ctx, 2); err != nil {
+		t.Fatalf("rebuilding stories: %v", err)
+	}
+
+	story, _ := store.GetByStory(ctx, "b-first")
+	if len(story) != 2 || story[0].ID != "b-first" || story[1].ID != "a-later" {
+		t.Fatalf("expected the earliest article to name a story of two, got %+v", story)
+	}
+	for _, id := range []string{"stale", "other"} {
+		if a, _ := store.GetByID(ctx, id, false); a.StoryID != id {
+			t.Errorf("expected %s to start its own story, got %s", id, a.StoryID)
+		}
+	}
+
+	if changed, _ := clusterer.Rebuild(ctx, 2); changed != 0 {
+		t.Errorf("expected a second rebuild to change nothing, got %d", changed)
+	}
+
+	incoming := []models.Article{
+		newArticle("incoming", "Man buries tenant alive in Haryana over affair with wife",
+			"The tenant was buried alive in a pit, police said", base.Add(5*time.Hour)),
+	}
+	if err := clusterer.Assign(ctx, incoming); err != nil {
+		t.Fatalf("assigning stories: %v", err)
+	}
+	if incoming[0].StoryID != "b-first" {
+		t.Errorf("expected the new article to join the existing story, got %s", incoming[0].StoryID)
+	}
+}
@@ -0,0 +1,119 @@
+package clustering
+
+import (
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+const (
+	signatureSize = 64
+
+	// Signatures are split into bands of bandRows values. Articles sharing a
+	// band are compared; with 32 bands of 2 rows, pairs at 0.5 similarity
+	// become candidates with near certainty.
+	bandRows = 2
+	bands    = signatureSize / bandRows
+)
+
+// Signature is the MinHash of an article's word set. The share of positions
+// where two signatures agree estimates the Jaccard similarity of the sets.
+type Signature [signatureSize]uint64
+
+// stopWords carry no signal about which story an article covers
+var stopWords = map[string]bool{
+	"a": true, "after": true, "an": true, "and": true, "are": true, "as": true,
+	"at": true, "be": true, "been": true, "but": true, "by": true, "for": true,
+	"from": true, "has": true, "have": true, "he": true, "her": true, "his": true,
+	"in": true, "is": true, "it": true, "its": true, "of": true, "on": true,
+	"or": true, "said": true, "says": true, "she": true, "that": true, "the": true,
+	"their": true, "they": true, "this": true, "to": true, "was": true, "were": true,
+	"which": true, "who": true, "will": true, "with": true,
+}
+
+// seeds derive the signatureSize hash functions from one base hash
+var seeds = func() [signatureSize]uint64 {
+	var s [signatureSize]uint64
+	x := uint64(0x9e3779b97f4a7c15)
+	for i := range s {
+		x = mix(x)
+		s[i] = x
+	}
+	return s
+}()
+
+// NewSignature computes the signature of an article's title and description.
+// The second result is false when the text has no usable words.
+func NewSignature(title, description string) (Signature, bool) {
+	var sig Signature
+	for i := range sig {
+		sig[i] = math.MaxUint64
+	}
+
+	words := tokenize(title + " " + description)
+	for _, word := range words {
+		h := fnv.New64a()
+		h.Write([]byte(word))
+		base := h.Sum64()
+
+		for i, seed := range seeds {
+			if v := mix(base ^ seed); v < sig[i] {
+				sig[i] = v
+			}
+		}
+	}
+
+	return sig, len(words) > 0
+}
+
+// Similarity estimates the Jaccard similarity of the word sets behind a and b
+func Similarity(a, b Signature) float64 {
+	same := 0
+	for i := range a {
+		if a[i] == b[i] {
+			same++
+		}
+	}
+	return float64(same) / signatureSize
+}
+
+type bandKey struct {
+	band   int
+	values [bandRows]uint64
+}
+
+func (s Signature) bandKeys() [bands]bandKey {
+	var keys [bands]bandKey
+	for b := range keys {
+		keys[b].band = b
+		copy(keys[b].values[:], s[b*bandRows:(b+1)*bandRows])
+	}
+	return keys
+}
+
+// mix is the splitmix64 finaliser
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+func tokenize(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	seen := make(map[string]bool, len(words))
+	tokens := words[:0]
+	for _, w := range words {
+		if len(w) > 1 && !stopWords[w] && !seen[w] {
+			seen[w] = true
+			tokens = append(tokens, w)
+		}
+	}
+	return tokens
+}
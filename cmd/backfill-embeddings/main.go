@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+
+	"inshorts-news-api/config"
+	"inshorts-news-api/db"
+	"inshorts-news-api/repositories"
+	"inshorts-news-api/services"
+	"inshorts-news-api/vectorindex"
+)
+
+func main() {
+	batchSize := flag.Int("batch-size", 100, "number of articles embedded per batch")
+	flag.Parse()
+
+	cfg := config.Load()
+
+	if err := db.Connect(cfg); err != nil {
+		log.Fatal("Database connection failed:", err)
+	}
+
+	llmProvider, err := services.NewLLMProvider(cfg)
+	if err != nil {
+		log.Fatal("LLM provider setup failed:", err)
+	}
+	llmService := services.NewLLMService(llmProvider, services.SummaryOptions{})
+
+	// The index only lives for this run; the server loads its own at startup
+	embeddingService := services.NewEmbeddingService(
+		repositories.NewEmbeddingRepository(db.GetDB()), llmService, vectorindex.New(vectorindex.Options{}))
+
+	generated, err := embeddingService.Backfill(context.Background(), repositories.NewArticleRepository(db.GetDB()), *batchSize)
+	if err != nil {
+		log.Fatalf("Backfill failed after %d embeddings: %v", generated, err)
+	}
+
+	log.Printf("Backfill completed, generated %d embeddings", generated)
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+
+	"inshorts-news-api/config"
+	"inshorts-news-api/db"
+	"inshorts-news-api/repositories"
+	"inshorts-news-api/services"
+)
+
+func main() {
+	batchSize := flag.Int("batch-size", 50, "number of articles summarised per batch")
+	flag.Parse()
+
+	cfg := config.Load()
+
+	if err := db.Connect(cfg); err != nil {
+		log.Fatal("Database connection failed:", err)
+	}
+
+	llmProvider, err := services.NewLLMProvider(cfg)
+	if err != nil {
+		log.Fatal("LLM provider setup failed:", err)
+	}
+
+	articleRepo := repositories.NewArticleRepository(db.GetDB())
+	summaryRepo := repositories.NewSummaryRepository(db.GetDB())
+
+	// No overall budget: unlike a request, the backfill can take its time
+	llmService := services.NewLLMService(llmProvider, services.SummaryOptions{
+		Concurrency: cfg.LLMSummaryConcurrency,
+		Timeout:     cfg.LLMSummaryTimeout,
+	})
+	summaryService := services.NewSummaryService(summaryRepo, llmService)
+
+	generated, err := summaryService.Backfill(context.Background(), articleRepo, *batchSize)
+	if err != nil {
+		log.Fatalf("Backfill failed after %d summaries: %v", generated, err)
+	}
+
+	log.Printf("Backfill completed, generated %d summaries", generated)
+}
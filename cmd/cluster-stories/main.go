@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+
+	"inshorts-news-api/clustering"
+	"inshorts-news-api/config"
+	"inshorts-news-api/db"
+	"inshorts-news-api/repositories"
+)
+
+func main() {
+	batchSize := flag.Int("batch-size", 500, "number of articles loaded per query")
+	flag.Parse()
+
+	cfg := config.Load()
+
+	if err := db.Connect(cfg); err != nil {
+		log.Fatal("Database connection failed:", err)
+	}
+
+	clusterer := clustering.NewClusterer(repositories.NewArticleRepository(db.GetDB()), clustering.Options{
+		Threshold: cfg.StorySimilarity,
+		Window:    cfg.StoryWindow,
+	})
+
+	changed, err := clusterer.Rebuild(context.Background(), *batchSize)
+	if err != nil {
+		log.Fatal("Clustering failed:", err)
+	}
+
+	log.Printf("Clustering completed, %d articles changed story", changed)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+
+	"inshorts-news-api/clustering"
+	"inshorts-news-api/config"
+	"inshorts-news-api/db"
+	"inshorts-news-api/ingestion"
+	"inshorts-news-api/repositories"
+)
+
+func main() {
+	cfg := config.Load()
+
+	feedsFile := flag.String("feeds", cfg.IngestFeedsFile, "JSON file listing the feeds to ingest")
+	flag.Parse()
+
+	if *feedsFile == "" {
+		log.Fatal("No feeds configured, set INGEST_FEEDS_FILE or pass -feeds")
+	}
+
+	feeds, err := ingestion.LoadFeeds(*feedsFile)
+	if err != nil {
+		log.Fatal("Feed configuration failed:", err)
+	}
+
+	if err := db.Connect(cfg); err != nil {
+		log.Fatal("Database connection failed:", err)
+	}
+
+	articleRepo := repositories.NewArticleRepository(db.GetDB())
+	clusterer := clustering.NewClusterer(articleRepo, clustering.Options{
+		Threshold: cfg.StorySimilarity,
+		Window:    cfg.StoryWindow,
+	})
+
+	worker := ingestion.NewWorker(feeds, articleRepo, repositories.NewFeedRepository(db.GetDB()), ingestion.Options{
+		Timeout:   cfg.IngestTimeout,
+		Clusterer: clusterer,
+	})
+
+	inserted := worker.PollAll(context.Background())
+	log.Printf("Ingestion completed, inserted %d articles from %d feeds", inserted, len(feeds))
+}
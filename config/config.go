@@ -3,6 +3,9 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -15,6 +18,71 @@ type Config struct {
 	DBName     string
 	ServerPort string
 	OpenAIKey  string
+
+	LLMProvider       string
+	LLMBaseURL        string
+	LLMModel          string
+	LLMEmbeddingModel string
+
+	LLMSummaryConcurrency int
+	LLMSummaryTimeout     time.Duration
+	LLMSummaryBudget      time.Duration
+
+	// Articles whose words overlap by StorySimilarity or more and that were
+	// published within StoryWindow of each other form one story
+	StorySimilarity float64
+	StoryWindow     time.Duration
+
+	// IngestFeedsFile is a JSON list of feeds to poll; ingestion is off when empty
+	IngestFeedsFile string
+	IngestInterval  time.Duration
+	IngestTimeout   time.Duration
+
+	// Trending ranking: the default algorithm, event weights, decay half-life,
+	// the interactions an article needs before it can trend and whether
+	// interactions are unique users rather than events
+	TrendingAlgorithm       string
+	TrendingWeights         map[string]float64
+	TrendingHalfLife        time.Duration
+	TrendingMinInteractions int
+	TrendingUniqueUsers     bool
+
+	// Trending scores are precomputed for TrendingWindows (in hours) every
+	// TrendingRefreshInterval, and served until TrendingStaleAfter old.
+	// Precomputing is off when the interval is zero.
+	TrendingRefreshInterval time.Duration
+	TrendingStaleAfter      time.Duration
+	TrendingWindows         []int
+
+	// Events: types accepted besides view, click and share, how far ahead of
+	// the server a client clock may be and how old a buffered event may be
+	EventExtraTypes   []string
+	EventMaxClockSkew time.Duration
+	EventMaxAge       time.Duration
+
+	// Events are buffered and stored in batches in the background; they are
+	// stored on the request path when EventBufferSize is zero. A batch that
+	// fails EventFlushRetries times in a row is dropped.
+	EventBufferSize    int
+	EventFlushBatch    int
+	EventFlushInterval time.Duration
+	EventFlushRetries  int
+	EventOverflow      string
+
+	// Abuse filtering: events per IP and article per FraudRateWindow before
+	// the rest are suspicious, how long repeated views count as one, and
+	// names of bots matched in user agents besides the built-in list
+	FraudRateLimit     int
+	FraudRateWindow    time.Duration
+	FraudViewSession   time.Duration
+	FraudBotUserAgents []string
+
+	// TrustedProxies are the addresses or CIDRs of proxies whose
+	// X-Forwarded-For header gives the client IP; none are trusted by default
+	TrustedProxies []string
+
+	// AdminTokens maps admin bearer tokens to the actor recorded in the audit log
+	AdminTokens map[string]string
 }
 
 func Load() *Config {
@@ -30,6 +98,60 @@ func Load() *Config {
 		DBName:     getEnv("DB_NAME", "inshorts_news"),
 		ServerPort: getEnv("SERVER_PORT", "8080"),
 		OpenAIKey:  getEnv("OPENAI_API_KEY", ""),
+
+		// openai, openai_compatible, offline or none
+		LLMProvider:       getEnv("LLM_PROVIDER", "openai"),
+		LLMBaseURL:        getEnv("LLM_BASE_URL", ""),
+		LLMModel:          getEnv("LLM_MODEL", "gpt-3.5-turbo"),
+		LLMEmbeddingModel: getEnv("LLM_EMBEDDING_MODEL", "text-embedding-ada-002"),
+
+		// Summaries run in parallel; Timeout bounds each call, Budget the whole batch
+		LLMSummaryConcurrency: getEnvInt("LLM_SUMMARY_CONCURRENCY", 4),
+		LLMSummaryTimeout:     getEnvDuration("LLM_SUMMARY_TIMEOUT", 8*time.Second),
+		LLMSummaryBudget:      getEnvDuration("LLM_SUMMARY_BUDGET", 15*time.Second),
+
+		StorySimilarity: getEnvFloat("STORY_SIMILARITY", 0.5),
+		StoryWindow:     getEnvDuration("STORY_WINDOW", 48*time.Hour),
+
+		IngestFeedsFile: getEnv("INGEST_FEEDS_FILE", ""),
+		IngestInterval:  getEnvDuration("INGEST_INTERVAL", 15*time.Minute),
+		IngestTimeout:   getEnvDuration("INGEST_TIMEOUT", 20*time.Second),
+
+		// classic, decay or velocity; weights are comma-separated type:weight
+		// pairs, e.g. "share:3,click:2,view:1"
+		TrendingAlgorithm:       getEnv("TRENDING_ALGORITHM", "classic"),
+		TrendingWeights:         getEnvWeights("TRENDING_WEIGHTS"),
+		TrendingHalfLife:        getEnvDuration("TRENDING_HALF_LIFE", 6*time.Hour),
+		TrendingMinInteractions: getEnvInt("TRENDING_MIN_INTERACTIONS", 0),
+		TrendingUniqueUsers:     getEnvBool("TRENDING_UNIQUE_USERS", false),
+		TrendingRefreshInterval: getEnvDuration("TRENDING_REFRESH_INTERVAL", time.Minute),
+		TrendingStaleAfter:      getEnvDuration("TRENDING_STALE_AFTER", 10*time.Minute),
+		TrendingWindows:         getEnvInts("TRENDING_WINDOWS", []int{24}),
+
+		// Comma-separated, e.g. "bookmark,dwell"
+		EventExtraTypes:   getEnvList("EVENT_EXTRA_TYPES"),
+		EventMaxClockSkew: getEnvDuration("EVENT_MAX_CLOCK_SKEW", 5*time.Minute),
+		EventMaxAge:       getEnvDuration("EVENT_MAX_AGE", 72*time.Hour),
+
+		// reject answers 503 when the buffer is full; drop-oldest discards
+		// the oldest buffered events instead
+		EventBufferSize:    getEnvInt("EVENT_BUFFER_SIZE", 10000),
+		EventFlushBatch:    getEnvInt("EVENT_FLUSH_BATCH", 500),
+		EventFlushInterval: getEnvDuration("EVENT_FLUSH_INTERVAL", time.Second),
+		EventFlushRetries:  getEnvInt("EVENT_FLUSH_RETRIES", 10),
+		EventOverflow:      getEnv("EVENT_OVERFLOW", "reject"),
+
+		// A zero limit or session turns that check off
+		FraudRateLimit:     getEnvInt("FRAUD_RATE_LIMIT", 30),
+		FraudRateWindow:    getEnvDuration("FRAUD_RATE_WINDOW", 10*time.Minute),
+		FraudViewSession:   getEnvDuration("FRAUD_VIEW_SESSION", 30*time.Minute),
+		FraudBotUserAgents: getEnvList("FRAUD_BOT_USER_AGENTS"),
+
+		// Comma-separated, e.g. "10.0.0.0/8,192.168.1.2"
+		TrustedProxies: getEnvList("TRUSTED_PROXIES"),
+
+		// Comma-separated actor:token pairs, e.g. "alice:s3cret,bob:t0ken"
+		AdminTokens: getEnvTokens("ADMIN_API_KEYS"),
 	}
 }
 
@@ -39,3 +161,102 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+		log.Printf("Invalid value for %s, using default %d", key, defaultValue)
+	}
+	return defaultValue
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+			return parsed
+		}
+		log.Printf("Invalid value for %s, using default %g", key, defaultValue)
+	}
+	return defaultValue
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			return parsed
+		}
+		log.Printf("Invalid value for %s, using default %t", key, defaultValue)
+	}
+	return defaultValue
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil {
+			return parsed
+		}
+		log.Printf("Invalid value for %s, using default %s", key, defaultValue)
+	}
+	return defaultValue
+}
+
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func getEnvTokens(key string) map[string]string {
+	tokens := make(map[string]string)
+	for _, pair := range strings.Split(os.Getenv(key), ",") {
+		actor, token, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok || actor == "" || token == "" {
+			if pair != "" {
+				log.Printf("Ignoring malformed entry in %s", key)
+			}
+			continue
+		}
+		tokens[token] = actor
+	}
+	return tokens
+}
+
+func getEnvWeights(key string) map[string]float64 {
+	weights := make(map[string]float64)
+	for _, pair := range strings.Split(os.Getenv(key), ",") {
+		eventType, raw, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		weight, err := strconv.ParseFloat(raw, 64)
+		if !ok || eventType == "" || err != nil {
+			if pair != "" {
+				log.Printf("Ignoring malformed entry in %s", key)
+			}
+			continue
+		}
+		weights[eventType] = weight
+	}
+	return weights
+}
+
+func getEnvInts(key string, defaultValue []int) []int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	var values []int
+	for _, raw := range strings.Split(value, ",") {
+		parsed, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil || parsed <= 0 {
+			log.Printf("Invalid value for %s, using default %v", key, defaultValue)
+			return defaultValue
+		}
+		values = append(values, parsed)
+	}
+	return values
+}
@@ -37,6 +37,12 @@ func AutoMigrate() error {
     return DB.AutoMigrate(
         &models.Article{},
         &models.UserEvent{},
+        &models.ArticleSummary{},
+        &models.ArticleAuditLog{},
+        &models.FeedStatus{},
+        &models.ArticleEmbedding{},
+        &models.TrendingScore{},
+        &models.TrendingCell{},
     )
 }
 
@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddWeightedSearchIndex, downAddWeightedSearchIndex)
+}
+
+func upAddWeightedSearchIndex(ctx context.Context, tx *sql.Tx) error {
+	// Must stay identical to searchVector in the article repository,
+	// otherwise the planner will not pick this index up
+	query := `CREATE INDEX IF NOT EXISTS idx_articles_weighted_fts
+         ON articles USING gin((
+             setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+             setweight(to_tsvector('english', coalesce(description, '')), 'B')
+         ))`
+
+	if _, err := tx.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to create weighted search index: %w", err)
+	}
+
+	return nil
+}
+
+func downAddWeightedSearchIndex(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `DROP INDEX IF EXISTS idx_articles_weighted_fts`); err != nil {
+		return fmt.Errorf("failed to drop weighted search index: %w", err)
+	}
+
+	return nil
+}
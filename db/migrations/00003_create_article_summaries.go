@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/pressly/goose/v3"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"inshorts-news-api/models"
+)
+
+func init() {
+	goose.AddMigrationContext(upCreateArticleSummariesTable, downCreateArticleSummariesTable)
+}
+
+func upCreateArticleSummariesTable(ctx context.Context, tx *sql.Tx) error {
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: tx,
+	}), &gorm.Config{})
+	if err != nil {
+		return fmt.Errorf("failed to create gorm instance: %w", err)
+	}
+
+	if err := gormDB.WithContext(ctx).AutoMigrate(&models.ArticleSummary{}); err != nil {
+		return fmt.Errorf("failed to auto-migrate: %w", err)
+	}
+
+	return nil
+}
+
+func downCreateArticleSummariesTable(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `DROP TABLE IF EXISTS article_summaries CASCADE`); err != nil {
+		return fmt.Errorf("failed to drop article_summaries: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/pressly/goose/v3"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"inshorts-news-api/models"
+)
+
+func init() {
+	goose.AddMigrationContext(upCreateArticleAuditLogsTable, downCreateArticleAuditLogsTable)
+}
+
+func upCreateArticleAuditLogsTable(ctx context.Context, tx *sql.Tx) error {
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: tx,
+	}), &gorm.Config{})
+	if err != nil {
+		return fmt.Errorf("failed to create gorm instance: %w", err)
+	}
+
+	if err := gormDB.WithContext(ctx).AutoMigrate(&models.ArticleAuditLog{}); err != nil {
+		return fmt.Errorf("failed to auto-migrate: %w", err)
+	}
+
+	return nil
+}
+
+func downCreateArticleAuditLogsTable(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `DROP TABLE IF EXISTS article_audit_logs CASCADE`); err != nil {
+		return fmt.Errorf("failed to drop article_audit_logs: %w", err)
+	}
+
+	return nil
+}
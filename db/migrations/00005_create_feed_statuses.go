@@ -0,0 +1,45 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/pressly/goose/v3"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"inshorts-news-api/models"
+)
+
+func init() {
+	goose.AddMigrationContext(upCreateFeedStatusesTable, downCreateFeedStatusesTable)
+}
+
+func upCreateFeedStatusesTable(ctx context.Context, tx *sql.Tx) error {
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: tx,
+	}), &gorm.Config{})
+	if err != nil {
+		return fmt.Errorf("failed to create gorm instance: %w", err)
+	}
+
+	// Articles gain idx_articles_url, which ingestion deduplicates on
+	if err := gormDB.WithContext(ctx).AutoMigrate(&models.Article{}, &models.FeedStatus{}); err != nil {
+		return fmt.Errorf("failed to auto-migrate: %w", err)
+	}
+
+	return nil
+}
+
+func downCreateFeedStatusesTable(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `DROP INDEX IF EXISTS idx_articles_url`); err != nil {
+		return fmt.Errorf("failed to drop idx_articles_url: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DROP TABLE IF EXISTS feed_statuses CASCADE`); err != nil {
+		return fmt.Errorf("failed to drop feed_statuses: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,34 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddArticleStoryID, downAddArticleStoryID)
+}
+
+// Existing articles get their story_id from cmd/cluster-stories
+func upAddArticleStoryID(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE articles ADD COLUMN IF NOT EXISTS story_id text`); err != nil {
+		return fmt.Errorf("failed to add story_id: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS idx_story_id ON articles (story_id)`); err != nil {
+		return fmt.Errorf("failed to create story_id index: %w", err)
+	}
+
+	return nil
+}
+
+func downAddArticleStoryID(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE articles DROP COLUMN IF EXISTS story_id`); err != nil {
+		return fmt.Errorf("failed to drop story_id: %w", err)
+	}
+
+	return nil
+}
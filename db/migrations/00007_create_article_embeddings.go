@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/pressly/goose/v3"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"inshorts-news-api/models"
+)
+
+func init() {
+	goose.AddMigrationContext(upCreateArticleEmbeddingsTable, downCreateArticleEmbeddingsTable)
+}
+
+func upCreateArticleEmbeddingsTable(ctx context.Context, tx *sql.Tx) error {
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: tx,
+	}), &gorm.Config{})
+	if err != nil {
+		return fmt.Errorf("failed to create gorm instance: %w", err)
+	}
+
+	if err := gormDB.WithContext(ctx).AutoMigrate(&models.ArticleEmbedding{}); err != nil {
+		return fmt.Errorf("failed to auto-migrate: %w", err)
+	}
+
+	return nil
+}
+
+func downCreateArticleEmbeddingsTable(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `DROP TABLE IF EXISTS article_embeddings CASCADE`); err != nil {
+		return fmt.Errorf("failed to drop article_embeddings: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddArticleGeography, downAddArticleGeography)
+}
+
+// geog is generated from latitude/longitude, so adding it fills in every
+// existing article and later writes keep it in step without a trigger
+func upAddArticleGeography(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `CREATE EXTENSION IF NOT EXISTS postgis`); err != nil {
+		return fmt.Errorf("failed to enable postgis: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		ALTER TABLE articles ADD COLUMN IF NOT EXISTS geog geography(Point, 4326)
+		GENERATED ALWAYS AS (ST_SetSRID(ST_MakePoint(longitude, latitude), 4326)::geography) STORED
+	`); err != nil {
+		return fmt.Errorf("failed to add geog: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS idx_articles_geog ON articles USING GIST (geog)`); err != nil {
+		return fmt.Errorf("failed to create geog index: %w", err)
+	}
+
+	// Radius queries go through the GiST index now
+	if _, err := tx.ExecContext(ctx, `DROP INDEX IF EXISTS idx_location`); err != nil {
+		return fmt.Errorf("failed to drop location index: %w", err)
+	}
+
+	return nil
+}
+
+func downAddArticleGeography(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS idx_location ON articles (latitude, longitude)`); err != nil {
+		return fmt.Errorf("failed to restore location index: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE articles DROP COLUMN IF EXISTS geog`); err != nil {
+		return fmt.Errorf("failed to drop geog: %w", err)
+	}
+
+	return nil
+}
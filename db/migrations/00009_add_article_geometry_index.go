@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddArticleGeometryIndex, downAddArticleGeometryIndex)
+}
+
+// Box and polygon queries compare in lon/lat as GeoJSON does, which needs
+// geog as a geometry
+func upAddArticleGeometryIndex(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS idx_articles_geom ON articles USING GIST ((geog::geometry))`); err != nil {
+		return fmt.Errorf("failed to create geometry index: %w", err)
+	}
+
+	return nil
+}
+
+func downAddArticleGeometryIndex(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `DROP INDEX IF EXISTS idx_articles_geom`); err != nil {
+		return fmt.Errorf("failed to drop geometry index: %w", err)
+	}
+
+	return nil
+}
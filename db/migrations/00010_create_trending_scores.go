@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/pressly/goose/v3"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"inshorts-news-api/models"
+)
+
+func init() {
+	goose.AddMigrationContext(upCreateTrendingScoresTables, downCreateTrendingScoresTables)
+}
+
+func upCreateTrendingScoresTables(ctx context.Context, tx *sql.Tx) error {
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: tx,
+	}), &gorm.Config{})
+	if err != nil {
+		return fmt.Errorf("failed to create gorm instance: %w", err)
+	}
+
+	if err := gormDB.WithContext(ctx).AutoMigrate(&models.TrendingScore{}, &models.TrendingCell{}); err != nil {
+		return fmt.Errorf("failed to auto-migrate: %w", err)
+	}
+
+	return nil
+}
+
+func downCreateTrendingScoresTables(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `DROP TABLE IF EXISTS trending_scores, trending_cells CASCADE`); err != nil {
+		return fmt.Errorf("failed to drop trending tables: %w", err)
+	}
+
+	return nil
+}
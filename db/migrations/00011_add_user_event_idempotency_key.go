@@ -0,0 +1,34 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddUserEventIdempotencyKey, downAddUserEventIdempotencyKey)
+}
+
+// Existing events have no key; NULLs never conflict in the unique index
+func upAddUserEventIdempotencyKey(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE user_events ADD COLUMN IF NOT EXISTS idempotency_key varchar(128)`); err != nil {
+		return fmt.Errorf("failed to add idempotency_key: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `CREATE UNIQUE INDEX IF NOT EXISTS idx_user_events_idempotency_key ON user_events (idempotency_key)`); err != nil {
+		return fmt.Errorf("failed to create idempotency_key index: %w", err)
+	}
+
+	return nil
+}
+
+func downAddUserEventIdempotencyKey(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE user_events DROP COLUMN IF EXISTS idempotency_key`); err != nil {
+		return fmt.Errorf("failed to drop idempotency_key: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddUserEventArticleFK, downAddUserEventArticleFK)
+}
+
+// Events about articles that no longer exist are deleted so the constraint
+// can be added. Events stored without a timestamp get their insert time,
+// so they count towards trending like events recorded since. Tables created
+// before events had an insert time get the column first.
+func upAddUserEventArticleFK(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE user_events ADD COLUMN IF NOT EXISTS created_at timestamptz`); err != nil {
+		return fmt.Errorf("failed to add created_at: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+        DELETE FROM user_events ue
+        WHERE NOT EXISTS (SELECT 1 FROM articles a WHERE a.id = ue.article_id)
+    `); err != nil {
+		return fmt.Errorf("failed to delete orphaned events: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+        UPDATE user_events SET timestamp = COALESCE(created_at, now())
+        WHERE timestamp IS NULL OR timestamp < '1970-01-01'
+    `); err != nil {
+		return fmt.Errorf("failed to backfill event timestamps: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+        ALTER TABLE user_events
+        ADD CONSTRAINT fk_user_events_article
+        FOREIGN KEY (article_id) REFERENCES articles (id) ON DELETE CASCADE
+    `); err != nil {
+		return fmt.Errorf("failed to add article foreign key: %w", err)
+	}
+
+	return nil
+}
+
+func downAddUserEventArticleFK(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE user_events DROP CONSTRAINT IF EXISTS fk_user_events_article`); err != nil {
+		return fmt.Errorf("failed to drop article foreign key: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddUserEventSuspicious, downAddUserEventSuspicious)
+}
+
+func upAddUserEventSuspicious(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `
+        ALTER TABLE user_events
+        ADD COLUMN IF NOT EXISTS suspicious boolean NOT NULL DEFAULT false,
+        ADD COLUMN IF NOT EXISTS suspicious_reason varchar(32)
+    `); err != nil {
+		return fmt.Errorf("failed to add suspicious columns: %w", err)
+	}
+
+	return nil
+}
+
+func downAddUserEventSuspicious(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `
+        ALTER TABLE user_events
+        DROP COLUMN IF EXISTS suspicious,
+        DROP COLUMN IF EXISTS suspicious_reason
+    `); err != nil {
+		return fmt.Errorf("failed to drop suspicious columns: %w", err)
+	}
+
+	return nil
+}
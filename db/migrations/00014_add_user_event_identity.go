@@ -0,0 +1,46 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddUserEventIdentity, downAddUserEventIdentity)
+}
+
+func upAddUserEventIdentity(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `
+        ALTER TABLE user_events
+        ADD COLUMN IF NOT EXISTS user_id varchar(64),
+        ADD COLUMN IF NOT EXISTS session_id varchar(64),
+        ADD COLUMN IF NOT EXISTS device_id varchar(64)
+    `); err != nil {
+		return fmt.Errorf("failed to add identity columns: %w", err)
+	}
+
+	for _, column := range []string{"user_id", "session_id", "device_id"} {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(
+			`CREATE INDEX IF NOT EXISTS idx_user_events_%[1]s ON user_events (%[1]s)`, column)); err != nil {
+			return fmt.Errorf("failed to create %s index: %w", column, err)
+		}
+	}
+
+	return nil
+}
+
+func downAddUserEventIdentity(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `
+        ALTER TABLE user_events
+        DROP COLUMN IF EXISTS user_id,
+        DROP COLUMN IF EXISTS session_id,
+        DROP COLUMN IF EXISTS device_id
+    `); err != nil {
+		return fmt.Errorf("failed to drop identity columns: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddTrendingCellEmpty, downAddTrendingCellEmpty)
+}
+
+// The trending refresh reads new events by insert time, and records cells
+// without scores instead of forgetting them
+func upAddTrendingCellEmpty(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS idx_user_events_created_at ON user_events (created_at)`); err != nil {
+		return fmt.Errorf("failed to create created_at index: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE trending_cells ADD COLUMN IF NOT EXISTS empty boolean NOT NULL DEFAULT false`); err != nil {
+		return fmt.Errorf("failed to add empty: %w", err)
+	}
+
+	return nil
+}
+
+func downAddTrendingCellEmpty(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE trending_cells DROP COLUMN IF EXISTS empty`); err != nil {
+		return fmt.Errorf("failed to drop empty: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DROP INDEX IF EXISTS idx_user_events_created_at`); err != nil {
+		return fmt.Errorf("failed to drop created_at index: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,369 @@
+// Package eventbus buffers user events in memory and stores them in
+// batches in the background, so recording an event does not wait on the
+// database.
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"inshorts-news-api/models"
+)
+
+// What Publish does when the buffer cannot hold the new events
+const (
+	// OverflowReject refuses the events with ErrFull
+	OverflowReject = "reject"
+	// OverflowDropOldest discards the oldest buffered events to make room
+	OverflowDropOldest = "drop-oldest"
+)
+
+var (
+	ErrFull   = errors.New("event buffer is full")
+	ErrClosed = errors.New("event bus is closed")
+)
+
+// Sink stores a batch of events, as ArticleStore.CreateUserEvents does
+type Sink interface {
+	CreateUserEvents(ctx context.Context, events []models.UserEvent) ([]bool, error)
+}
+
+type Options struct {
+	// Capacity is the most events buffered at once
+	Capacity int
+	// BatchSize is the most events stored per insert. A full batch is
+	// flushed straight away rather than at the next interval.
+	BatchSize int
+	// FlushInterval is the longest an event waits in the buffer
+	FlushInterval time.Duration
+	// FlushTimeout bounds a single insert
+	FlushTimeout time.Duration
+	// MaxRetries is how many times in a row a batch may fail before it is
+	// dropped
+	MaxRetries int
+	// Overflow is OverflowReject or OverflowDropOldest
+	Overflow string
+}
+
+// WithDefaults fills in the settings that were left empty
+func (o Options) WithDefaults() Options {
+	if o.Capacity <= 0 {
+		o.Capacity = 10000
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = 500
+	}
+	if o.FlushInterval <= 0 {
+		o.FlushInterval = time.Second
+	}
+	if o.FlushTimeout <= 0 {
+		o.FlushTimeout = 10 * time.Second
+	}
+	if o.MaxRetries <= 0 {
+		o.MaxRetries = 10
+	}
+	if o.Overflow == "" {
+		o.Overflow = OverflowReject
+	}
+	return o
+}
+
+func (o Options) Validate() error {
+	if o.Overflow != OverflowReject && o.Overflow != OverflowDropOldest {
+		return fmt.Errorf("unknown overflow policy %q, expected %s or %s", o.Overflow, OverflowReject, OverflowDropOldest)
+	}
+	return nil
+}
+
+// Stats are counters since the bus was created, for monitoring. Failed
+// counts the events dropped because they could not be stored.
+type Stats struct {
+	Depth       int    `json:"depth"`
+	Capacity    int    `json:"capacity"`
+	Overflow    string `json:"overflow"`
+	Published   int64  `json:"published"`
+	Flushed     int64  `json:"flushed"`
+	Dropped     int64  `json:"dropped"`
+	Rejected    int64  `json:"rejected"`
+	Failed      int64  `json:"failed"`
+	FlushErrors int64  `json:"flush_errors"`
+}
+
+// Bus is a bounded buffer of events with a background flusher. A failed
+// flush puts its batch back at the head of the buffer to be retried, which
+// is safe for events with idempotency keys, until it has failed MaxRetries
+// times in a row. A batch the store refuses as invalid is split up instead,
+// so only the events that cannot be stored are dropped.
+type Bus struct {
+	sink    Sink
+	options Options
+
+	mu       sync.Mutex
+	queue    []models.UserEvent
+	pending  map[string]bool // idempotency keys buffered or being stored
+	closed   bool
+	stats    Stats
+	failures int
+
+	wake chan struct{}
+	stop chan struct{}
+	done chan struct{}
+}
+
+// New starts the flusher of a bus; Close stops it
+func New(sink Sink, options Options) *Bus {
+	options = options.WithDefaults()
+	b := &Bus{
+		sink:    sink,
+		options: options,
+		pending: make(map[string]bool),
+		stats:   Stats{Capacity: options.Capacity, Overflow: options.Overflow},
+		wake:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+// Publish buffers events to be stored and reports which it buffered.
+// Events whose idempotency key is already buffered or being stored, or used
+// by an earlier event, are left out as duplicates. All of the others are
+// buffered, or none and ErrFull is returned.
+func (b *Bus) Publish(events ...models.UserEvent) ([]bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil, ErrClosed
+	}
+
+	queued := make([]bool, len(events))
+	fresh := make([]models.UserEvent, 0, len(events))
+	keys := make(map[string]bool)
+	for i, event := range events {
+		if key := event.IdempotencyKey; key != nil {
+			if b.pending[*key] || keys[*key] {
+				continue
+			}
+			keys[*key] = true
+		}
+		queued[i] = true
+		fresh = append(fresh, event)
+	}
+
+	if len(fresh) > b.options.Capacity {
+		b.stats.Rejected += int64(len(fresh))
+		return nil, ErrFull
+	}
+
+	if overflow := len(b.queue) + len(fresh) - b.options.Capacity; overflow > 0 {
+		if b.options.Overflow == OverflowReject {
+			b.stats.Rejected += int64(len(fresh))
+			return nil, ErrFull
+		}
+		b.release(b.queue[:overflow])
+		b.queue = b.queue[overflow:]
+		b.stats.Dropped += int64(overflow)
+	}
+
+	for key := range keys {
+		b.pending[key] = true
+	}
+	b.queue = append(b.queue, fresh...)
+	b.stats.Published += int64(len(fresh))
+	if len(b.queue) >= b.options.BatchSize {
+		select {
+		case b.wake <- struct{}{}:
+		default:
+		}
+	}
+	return queued, nil
+}
+
+// release forgets the idempotency keys of events that were stored or
+// dropped; b.mu must be held
+func (b *Bus) release(events []models.UserEvent) {
+	for _, event := range events {
+		if event.IdempotencyKey != nil {
+			delete(b.pending, *event.IdempotencyKey)
+		}
+	}
+}
+
+func (b *Bus) Stats() Stats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stats := b.stats
+	stats.Depth = len(b.queue)
+	return stats
+}
+
+// Close stops accepting events and waits until the buffered ones are
+// stored or ctx is done. Events that still fail to store while draining
+// are dropped.
+func (b *Bus) Close(ctx context.Context) error {
+	b.mu.Lock()
+	if !b.closed {
+		b.closed = true
+		close(b.stop)
+	}
+	b.mu.Unlock()
+
+	select {
+	case <-b.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *Bus) run() {
+	defer close(b.done)
+
+	ticker := time.NewTicker(b.options.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.wake:
+		case <-ticker.C:
+		case <-b.stop:
+			b.drain()
+			return
+		}
+
+		for b.Stats().Depth > 0 {
+			if err := b.flush(); err != nil {
+				log.Printf("Storing buffered events failed: %v", err)
+				break
+			}
+		}
+	}
+}
+
+// drain flushes until the buffer is empty or a flush fails, and drops
+// whatever is left
+func (b *Bus) drain() {
+	for b.Stats().Depth > 0 {
+		if err := b.flush(); err != nil {
+			b.mu.Lock()
+			dropped := len(b.queue)
+			b.stats.Dropped += int64(dropped)
+			b.release(b.queue)
+			b.queue = nil
+			b.mu.Unlock()
+			log.Printf("Dropped %d buffered events on shutdown: %v", dropped, err)
+			return
+		}
+	}
+}
+
+// flush stores one batch from the head of the buffer
+func (b *Bus) flush() error {
+	b.mu.Lock()
+	n := min(len(b.queue), b.options.BatchSize)
+	batch := make([]models.UserEvent, n)
+	copy(batch, b.queue)
+	b.queue = b.queue[n:]
+	b.mu.Unlock()
+
+	err := b.store(batch)
+	if err == nil {
+		b.mu.Lock()
+		b.failures = 0
+		b.stats.Flushed += int64(len(batch))
+		b.release(batch)
+		b.mu.Unlock()
+		return nil
+	}
+
+	b.mu.Lock()
+	b.stats.FlushErrors++
+	b.failures++
+	if !isPermanent(err) && b.failures < b.options.MaxRetries {
+		if room := b.options.Capacity - len(b.queue); room >= len(batch) {
+			b.queue = append(batch, b.queue...)
+		} else {
+			b.stats.Dropped += int64(len(batch))
+			b.release(batch)
+		}
+		b.mu.Unlock()
+		return err
+	}
+	b.failures = 0
+	if !isPermanent(err) {
+		// The store is down rather than refusing these events, so storing
+		// them one by one would only time out once per event
+		b.stats.Dropped += int64(len(batch))
+		b.release(batch)
+		b.mu.Unlock()
+		log.Printf("Dropped %d events after %d failed flushes: %v", len(batch), b.options.MaxRetries, err)
+		return err
+	}
+	b.mu.Unlock()
+
+	b.isolate(batch, err)
+	return nil
+}
+
+// isolate stores what it can of a batch the store refused with err, halving
+// it until the events that fail on their own are found, and drops those. A
+// half that fails for any other reason is dropped whole.
+func (b *Bus) isolate(batch []models.UserEvent, err error) {
+	if len(batch) == 1 {
+		b.mu.Lock()
+		b.stats.Failed++
+		b.release(batch)
+		b.mu.Unlock()
+		log.Printf("Dropped event of article %s that could not be stored: %v", batch[0].ArticleID, err)
+		return
+	}
+
+	for _, half := range [][]models.UserEvent{batch[:len(batch)/2], batch[len(batch)/2:]} {
+		if err := b.store(half); err != nil {
+			b.mu.Lock()
+			b.stats.FlushErrors++
+			if !isPermanent(err) {
+				b.stats.Dropped += int64(len(half))
+				b.release(half)
+				b.mu.Unlock()
+				log.Printf("Dropped %d events that could not be stored: %v", len(half), err)
+				continue
+			}
+			b.mu.Unlock()
+			b.isolate(half, err)
+			continue
+		}
+		b.mu.Lock()
+		b.stats.Flushed += int64(len(half))
+		b.release(half)
+		b.mu.Unlock()
+	}
+}
+
+func (b *Bus) store(batch []models.UserEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), b.options.FlushTimeout)
+	defer cancel()
+
+	_, err := b.sink.CreateUserEvents(ctx, batch)
+	return err
+}
+
+// isPermanent reports whether the store refused the events themselves, as
+// Postgres does with data exceptions (SQLSTATE class 22) and constraint
+// violations (class 23), so retrying them as they are cannot succeed
+func isPermanent(err error) bool {
+	var pgErr interface{ SQLState() string }
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	state := pgErr.SQLState()
+	return strings.HasPrefix(state, "22") || strings.HasPrefix(state, "23")
+}
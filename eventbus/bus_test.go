@@ -0,0 +1,219 @@
+package eventbus_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"inshorts-news-api/eventbus"
+	"inshorts-news-api/models"
+	"inshorts-news-api/repositories"
+)
+
+// flakySink fails as many inserts as failures says, then stores events.
+// Inserts holding an event of an article in refuse fail with its error. It
+// blocks inserts while gate is held.
+type flakySink struct {
+	mu       sync.Mutex
+	store    *repositories.MemoryArticleStore
+	failures int
+	refuse   map[string]error
+	batches  []int
+	gate     sync.Mutex
+}
+
+// pgError stands in for a Postgres error with an SQLSTATE code
+type pgError string
+
+func (e pgError) Error() string    { return "SQLSTATE " + string(e) }
+func (e pgError) SQLState() string { return string(e) }
+
+func (s *flakySink) CreateUserEvents(ctx context.Context, events []models.UserEvent) ([]bool, error) {
+	s.gate.Lock()
+	defer s.gate.Unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failures > 0 {
+		s.failures--
+		return nil, errors.New("database is down")
+	}
+	for _, event := range events {
+		if err, ok := s.refuse[event.ArticleID]; ok {
+			return nil, err
+		}
+	}
+	s.batches = append(s.batches, len(events))
+	return s.store.CreateUserEvents(ctx, events)
+}
+
+func events(n int) []models.UserEvent {
+	events := make([]models.UserEvent, n)
+	for i := range events {
+		events[i] = models.UserEvent{ArticleID: "a", EventType: models.EventView, Timestamp: time.Now()}
+	}
+	return events
+}
+
+func TestBusFlushesInBatchesAndDrainsOnClose(t *testing.T) {
+	sink := &flakySink{store: repositories.NewMemoryArticleStore(), failures: 1}
+	bus := eventbus.New(sink, eventbus.Options{BatchSize: 4, FlushInterval: time.Hour})
+
+	// A full batch is flushed without waiting for the interval, and retried
+	// after the first insert fails
+	if _, err := bus.Publish(events(4)...); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	if _, err := bus.Publish(events(2)...); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for bus.Stats().FlushErrors == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := bus.Close(context.Background()); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	if got := len(sink.store.Events()); got != 6 {
+		t.Fatalf("expected all 6 events stored after close, got %d", got)
+	}
+	for _, size := range sink.batches {
+		if size > 4 {
+			t.Fatalf("expected batches of at most 4 events, got %v", sink.batches)
+		}
+	}
+
+	stats := bus.Stats()
+	if stats.Published != 6 || stats.Flushed != 6 || stats.FlushErrors != 1 || stats.Depth != 0 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	if _, err := bus.Publish(events(1)...); !errors.Is(err, eventbus.ErrClosed) {
+		t.Fatalf("expected ErrClosed after close, got %v", err)
+	}
+}
+
+func TestBusOverflow(t *testing.T) {
+	for _, tc := range []struct {
+		overflow string
+		err      error
+		stored   int
+		dropped  int64
+		rejected int64
+	}{
+		{eventbus.OverflowReject, eventbus.ErrFull, 3, 0, 2},
+		{eventbus.OverflowDropOldest, nil, 3, 2, 0},
+	} {
+		t.Run(tc.overflow, func(t *testing.T) {
+			sink := &flakySink{store: repositories.NewMemoryArticleStore()}
+			// Hold inserts back so events stay buffered
+			sink.gate.Lock()
+			bus := eventbus.New(sink, eventbus.Options{Capacity: 3, BatchSize: 10, FlushInterval: time.Hour, Overflow: tc.overflow})
+
+			if _, err := bus.Publish(events(3)...); err != nil {
+				t.Fatalf("publish failed: %v", err)
+			}
+			if _, err := bus.Publish(events(2)...); !errors.Is(err, tc.err) {
+				t.Fatalf("expected %v on overflow, got %v", tc.err, err)
+			}
+			if _, err := bus.Publish(events(4)...); !errors.Is(err, eventbus.ErrFull) {
+				t.Fatalf("expected ErrFull for more events than the capacity, got %v", err)
+			}
+
+			sink.gate.Unlock()
+			if err := bus.Close(context.Background()); err != nil {
+				t.Fatalf("close failed: %v", err)
+			}
+
+			stats := bus.Stats()
+			if got := len(sink.store.Events()); got != tc.stored || stats.Dropped != tc.dropped || stats.Rejected != tc.rejected+4 {
+				t.Fatalf("expected %d stored, %d dropped and %d rejected, got %d stored and %+v", tc.stored, tc.dropped, tc.rejected+4, got, stats)
+			}
+		})
+	}
+}
+
+func TestBusDropsOnlyEventsThatCannotBeStored(t *testing.T) {
+	// A constraint violation is refused at once rather than retried
+	sink := &flakySink{store: repositories.NewMemoryArticleStore(), refuse: map[string]error{"bad": pgError("23503")}}
+	bus := eventbus.New(sink, eventbus.Options{BatchSize: 7, FlushInterval: 10 * time.Millisecond, MaxRetries: 2})
+
+	batch := events(7)
+	batch[3].ArticleID = "bad"
+	if _, err := bus.Publish(batch...); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for bus.Stats().Failed == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := bus.Close(context.Background()); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	stats := bus.Stats()
+	if got := len(sink.store.Events()); got != 6 || stats.Flushed != 6 || stats.Failed != 1 || stats.Depth != 0 {
+		t.Fatalf("expected the 6 good events stored and the bad one dropped, got %d stored and %+v", got, stats)
+	}
+}
+
+func TestBusDropsBatchWhenStoreStaysDown(t *testing.T) {
+	const retries = 3
+	sink := &flakySink{store: repositories.NewMemoryArticleStore(), failures: 1000}
+	bus := eventbus.New(sink, eventbus.Options{BatchSize: 8, FlushInterval: 10 * time.Millisecond, MaxRetries: retries})
+
+	if _, err := bus.Publish(events(8)...); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for bus.Stats().Dropped == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := bus.Close(context.Background()); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	stats := bus.Stats()
+	if stats.Dropped != 8 || stats.Failed != 0 || stats.Flushed != 0 || stats.Depth != 0 {
+		t.Fatalf("expected the batch dropped whole, got %+v", stats)
+	}
+	// The batch was not split up into inserts that would each fail again
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if attempts := 1000 - sink.failures; attempts != retries || stats.FlushErrors != retries {
+		t.Fatalf("expected %d inserts, got %d and %+v", retries, attempts, stats)
+	}
+}
+
+func TestBusSkipsDuplicateKeys(t *testing.T) {
+	sink := &flakySink{store: repositories.NewMemoryArticleStore()}
+	// Hold inserts back so the first events stay buffered
+	sink.gate.Lock()
+	bus := eventbus.New(sink, eventbus.Options{BatchSize: 10, FlushInterval: time.Hour})
+
+	keyed := func(keys ...string) []models.UserEvent {
+		batch := events(len(keys))
+		for i := range keys {
+			if keys[i] != "" {
+				batch[i].IdempotencyKey = &keys[i]
+			}
+		}
+		return batch
+	}
+	if queued, err := bus.Publish(keyed("k1", "k2", "k1")...); err != nil || !queued[0] || !queued[1] || queued[2] {
+		t.Fatalf("expected the repeated key to be left out, got %v %v", queued, err)
+	}
+	if queued, err := bus.Publish(keyed("k2", "k3", "")...); err != nil || queued[0] || !queued[1] || !queued[2] {
+		t.Fatalf("expected the buffered key to be left out, got %v %v", queued, err)
+	}
+
+	sink.gate.Unlock()
+	if err := bus.Close(context.Background()); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	if stats := bus.Stats(); len(sink.store.Events()) != 4 || stats.Published != 4 {
+		t.Fatalf("expected 4 events stored, got %d and %+v", len(sink.store.Events()), stats)
+	}
+}
@@ -0,0 +1,180 @@
+// Package fraud screens user events for bots and abuse before they are
+// stored, so a single client cannot push an article up the trending list.
+package fraud
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"inshorts-news-api/models"
+)
+
+// Reasons an event is discarded or marked suspicious
+const (
+	ReasonBot          = "bot"
+	ReasonRepeatedView = "repeated_view"
+	ReasonRateLimited  = "rate_limited"
+)
+
+// DefaultBotUserAgents are the names of crawlers, link preview fetchers and
+// headless browsers, matched case-insensitively against whole words of a
+// user agent. HTTP libraries are left out: apps send events with them.
+var DefaultBotUserAgents = []string{
+	"bot", "robot", "crawler", "spider", "slurp",
+	"googlebot", "bingbot", "yandexbot", "duckduckbot", "baiduspider", "applebot",
+	"ahrefsbot", "semrushbot", "mj12bot", "petalbot", "gptbot", "ccbot",
+	"facebookexternalhit", "twitterbot", "linkedinbot", "slackbot", "discordbot", "telegrambot",
+	"headlesschrome", "phantomjs", "scrapy",
+}
+
+type Options struct {
+	// RateLimit is how many events a client IP may send about one article
+	// per RateWindow before the rest are marked suspicious; 0 disables it
+	RateLimit  int
+	RateWindow time.Duration
+	// ViewSession is how long, by the server's clock, repeated views of an
+	// article by one client count as one; 0 disables collapsing
+	ViewSession time.Duration
+	// BotUserAgents are names matched in addition to DefaultBotUserAgents
+	BotUserAgents []string
+}
+
+func (o Options) Validate() error {
+	if o.RateLimit < 0 {
+		return errors.New("rate limit cannot be negative")
+	}
+	if o.RateLimit > 0 && o.RateWindow <= 0 {
+		return errors.New("rate window must be positive when a rate limit is set")
+	}
+	if o.ViewSession < 0 {
+		return errors.New("view session cannot be negative")
+	}
+	return nil
+}
+
+// Filter keeps recent activity per client in memory. Each instance only
+// sees the events of its own process.
+type Filter struct {
+	options   Options
+	bots      map[string]bool
+	now       func() time.Time
+	mu        sync.Mutex
+	rates     map[rateKey]*rateWindow
+	views     map[viewKey]time.Time
+	lastSweep time.Time
+}
+
+type rateKey struct {
+	ip        string
+	articleID string
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+type viewKey struct {
+	ip        string
+	userAgent string
+	articleID string
+}
+
+func NewFilter(options Options) *Filter {
+	bots := make(map[string]bool, len(DefaultBotUserAgents)+len(options.BotUserAgents))
+	for _, name := range append(append([]string{}, DefaultBotUserAgents...), options.BotUserAgents...) {
+		for _, word := range userAgentWords(name) {
+			bots[word] = true
+		}
+	}
+
+	return &Filter{
+		options: options,
+		bots:    bots,
+		now:     time.Now,
+		rates:   make(map[rateKey]*rateWindow),
+		views:   make(map[viewKey]time.Time),
+	}
+}
+
+// Check screens an event from client and records it. It returns false and
+// a reason when the event should be discarded, and marks events over the
+// rate limit as suspicious. Each event must be checked once.
+func (f *Filter) Check(client models.EventClient, event *models.UserEvent) (string, bool) {
+	if f.isBot(client.UserAgent) {
+		return ReasonBot, false
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := f.now()
+	f.sweep(now)
+
+	// Sessions run on the server's clock: event timestamps come from the
+	// client, which could backdate each view into a session of its own
+	if f.options.ViewSession > 0 && event.EventType == models.EventView {
+		key := viewKey{ip: client.IP, userAgent: client.UserAgent, articleID: event.ArticleID}
+		if first, ok := f.views[key]; ok && now.Sub(first) < f.options.ViewSession {
+			return ReasonRepeatedView, false
+		}
+		f.views[key] = now
+	}
+
+	if f.options.RateLimit > 0 {
+		key := rateKey{ip: client.IP, articleID: event.ArticleID}
+		window, ok := f.rates[key]
+		if !ok || now.Sub(window.start) >= f.options.RateWindow {
+			window = &rateWindow{start: now}
+			f.rates[key] = window
+		}
+		window.count++
+		if window.count > f.options.RateLimit {
+			event.Suspicious = true
+			event.SuspiciousReason = ReasonRateLimited
+		}
+	}
+
+	return "", true
+}
+
+func (f *Filter) isBot(userAgent string) bool {
+	for _, word := range userAgentWords(userAgent) {
+		if f.bots[word] {
+			return true
+		}
+	}
+	return false
+}
+
+// userAgentWords splits a user agent into lower-case words of letters,
+// digits, dashes and underscores, so "Googlebot/2.1" holds "googlebot" but
+// "CUBOT X30" does not hold "bot"
+func userAgentWords(userAgent string) []string {
+	return strings.FieldsFunc(strings.ToLower(userAgent), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_'
+	})
+}
+
+// sweep forgets rate windows and view sessions that have ended, at most
+// once a minute
+func (f *Filter) sweep(now time.Time) {
+	if now.Sub(f.lastSweep) < time.Minute {
+		return
+	}
+	f.lastSweep = now
+
+	for key, window := range f.rates {
+		if now.Sub(window.start) >= f.options.RateWindow {
+			delete(f.rates, key)
+		}
+	}
+	for key, first := range f.views {
+		if now.Sub(first) >= f.options.ViewSession {
+			delete(f.views, key)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package fraud
+
+import (
+	"testing"
+	"time"
+
+	"inshorts-news-api/models"
+)
+
+func TestFilter(t *testing.T) {
+	now := time.Date(2025, 3, 26, 12, 0, 0, 0, time.UTC)
+	filter := NewFilter(Options{
+		RateLimit:     2,
+		RateWindow:    time.Minute,
+		ViewSession:   30 * time.Minute,
+		BotUserAgents: []string{"InternalMonitor"},
+	})
+	filter.now = func() time.Time { return now }
+
+	phone := models.EventClient{IP: "10.0.0.1", UserAgent: "NewsApp/3.1"}
+	check := func(client models.EventClient, eventType string, at time.Time) (models.UserEvent, string, bool) {
+		event := models.UserEvent{ArticleID: "a", EventType: eventType, Timestamp: at}
+		reason, keep := filter.Check(client, &event)
+		return event, reason, keep
+	}
+
+	for _, userAgent := range []string{
+		"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+		"Mozilla/5.0 (compatible; bingbot/2.0)",
+		"internalmonitor/1.0",
+	} {
+		if reason, keep := filter.Check(models.EventClient{IP: "10.0.0.2", UserAgent: userAgent}, &models.UserEvent{ArticleID: "a"}); keep || reason != ReasonBot {
+			t.Errorf("%s: expected a bot, got %q %v", userAgent, reason, keep)
+		}
+	}
+
+	// Apps send events with HTTP libraries, and device names may contain
+	// bot names
+	for _, userAgent := range []string{
+		"okhttp/4.12.0",
+		"Dalvik/2.1.0 (Linux; U; Android 13; CUBOT KINGKONG 9 Build/TP1A.220624.014)",
+		"Go-http-client/2.0",
+	} {
+		if reason, keep := filter.Check(models.EventClient{IP: "10.0.0.4", UserAgent: userAgent}, &models.UserEvent{ArticleID: userAgent}); !keep {
+			t.Errorf("%s: expected an app, got %q", userAgent, reason)
+		}
+	}
+
+	// Sessions follow the server's clock, so views backdated a session
+	// apart still collapse when they arrive together
+	if _, _, keep := check(phone, models.EventView, now); !keep {
+		t.Fatal("expected the first view to be kept")
+	}
+	for _, back := range []time.Duration{10 * time.Minute, 31 * time.Minute, 2 * time.Hour} {
+		if _, reason, keep := check(phone, models.EventView, now.Add(-back)); keep || reason != ReasonRepeatedView {
+			t.Fatalf("expected a view backdated %s to collapse, got %q %v", back, reason, keep)
+		}
+	}
+	if _, _, keep := check(models.EventClient{IP: "10.0.0.3", UserAgent: "NewsApp/3.1"}, models.EventView, now); !keep {
+		t.Fatal("expected another client's view to be kept")
+	}
+
+	// The kept view counts towards the limit of 2
+	if event, _, _ := check(phone, models.EventShare, now); event.Suspicious {
+		t.Fatal("expected the second event to be within the limit")
+	}
+	if event, _, keep := check(phone, models.EventShare, now); !keep || !event.Suspicious || event.SuspiciousReason != ReasonRateLimited {
+		t.Fatalf("expected the third event to be kept as suspicious, got %+v", event)
+	}
+
+	// Windows and sessions end, and are swept from memory
+	now = now.Add(31 * time.Minute)
+	if event, _, keep := check(phone, models.EventView, now); !keep || event.Suspicious {
+		t.Fatalf("expected a view in a new session and window to count, got %+v %v", event, keep)
+	}
+	if len(filter.rates) != 1 || len(filter.views) != 1 {
+		t.Fatalf("expected ended windows to be swept, got %d rates and %d views", len(filter.rates), len(filter.views))
+	}
+}
+
+func TestOptionsValidate(t *testing.T) {
+	valid := []Options{
+		{},
+		{RateLimit: 5, RateWindow: time.Minute, ViewSession: 30 * time.Minute},
+	}
+	for _, options := range valid {
+		if err := options.Validate(); err != nil {
+			t.Errorf("%+v: unexpected error %v", options, err)
+		}
+	}
+
+	invalid := []Options{
+		{RateLimit: 5},
+		{RateLimit: 5, RateWindow: -time.Minute},
+		{RateLimit: -1, RateWindow: time.Minute},
+		{ViewSession: -time.Minute},
+	}
+	for _, options := range invalid {
+		if err := options.Validate(); err == nil {
+			t.Errorf("%+v: expected an error", options)
+		}
+	}
+}
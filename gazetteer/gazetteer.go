@@ -0,0 +1,121 @@
+// Package gazetteer resolves place names to coordinates offline, from a
+// bundled list of Indian states and cities and a few world cities.
+package gazetteer
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Default search radius around a place that does not set its own
+const defaultCityRadiusKm = 15
+
+//go:embed places.json
+var bundledPlaces []byte
+
+type Place struct {
+	Name      string   `json:"name"`
+	Aliases   []string `json:"aliases,omitempty"`
+	Kind      string   `json:"kind"` // city or state
+	State     string   `json:"state,omitempty"`
+	Country   string   `json:"country,omitempty"`
+	Latitude  float64  `json:"lat"`
+	Longitude float64  `json:"lon"`
+	RadiusKm  float64  `json:"radius_km,omitempty"`
+}
+
+// Gazetteer looks places up by name or alias, ignoring case and
+// punctuation. A nil Gazetteer knows no places.
+type Gazetteer struct {
+	byName   map[string]Place
+	maxWords int
+}
+
+func New(places []Place) (*Gazetteer, error) {
+	g := &Gazetteer{byName: make(map[string]Place)}
+	for _, place := range places {
+		if place.RadiusKm <= 0 {
+			place.RadiusKm = defaultCityRadiusKm
+		}
+		for _, name := range append([]string{place.Name}, place.Aliases...) {
+			key := normalize(name)
+			if key == "" {
+				return nil, fmt.Errorf("place %q has an empty name", place.Name)
+			}
+			if other, ok := g.byName[key]; ok {
+				return nil, fmt.Errorf("%q names both %s and %s", name, other.Name, place.Name)
+			}
+			g.byName[key] = place
+			if n := len(strings.Fields(key)); n > g.maxWords {
+				g.maxWords = n
+			}
+		}
+	}
+	return g, nil
+}
+
+var (
+	bundledOnce sync.Once
+	bundled     *Gazetteer
+)
+
+// Bundled returns the gazetteer of the dataset compiled into the binary
+func Bundled() *Gazetteer {
+	bundledOnce.Do(func() {
+		var places []Place
+		if err := json.Unmarshal(bundledPlaces, &places); err != nil {
+			panic("gazetteer: bundled places are invalid: " + err.Error())
+		}
+		g, err := New(places)
+		if err != nil {
+			panic("gazetteer: " + err.Error())
+		}
+		bundled = g
+	})
+	return bundled
+}
+
+// Lookup finds the place called exactly name
+func (g *Gazetteer) Lookup(name string) (Place, bool) {
+	if g == nil {
+		return Place{}, false
+	}
+	place, ok := g.byName[normalize(name)]
+	return place, ok
+}
+
+// Find returns the place named in text, such as "news near Hazaribagh".
+// The longest name wins, so "Navi Mumbai" is preferred over "Mumbai", and
+// the earliest among names of the same length.
+func (g *Gazetteer) Find(text string) (Place, bool) {
+	if g == nil {
+		return Place{}, false
+	}
+
+	words := strings.Fields(normalize(text))
+	for n := min(g.maxWords, len(words)); n > 0; n-- {
+		for i := 0; i+n <= len(words); i++ {
+			if place, ok := g.byName[strings.Join(words[i:i+n], " ")]; ok {
+				return place, true
+			}
+		}
+	}
+	return Place{}, false
+}
+
+// normalize lowercases name and turns punctuation into spaces, keeping "&"
+// which appears in names such as "J&K"
+func normalize(name string) string {
+	return strings.Join(strings.Fields(strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '&':
+			return unicode.ToLower(r)
+		default:
+			return ' '
+		}
+	}, name)), " ")
+}
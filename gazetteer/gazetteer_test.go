@@ -0,0 +1,60 @@
+package gazetteer_test
+
+import (
+	"testing"
+
+	"inshorts-news-api/gazetteer"
+)
+
+func TestBundledResolvesNamesAndAliases(t *testing.T) {
+	places := gazetteer.Bundled()
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Hazaribagh", "Hazaribagh"},
+		{"hazaribag", "Hazaribagh"},
+		{"BANGALORE", "Bengaluru"},
+		{"news near Hazaribagh", "Hazaribagh"},
+		{"floods in navi mumbai today", "Navi Mumbai"},
+		{"what's happening in Tamil Nadu?", "Tamil Nadu"},
+		{"Washington D.C.", "Washington"},
+	}
+
+	for _, tt := range tests {
+		place, ok := places.Lookup(tt.text)
+		if !ok {
+			place, ok = places.Find(tt.text)
+		}
+		if !ok || place.Name != tt.want {
+			t.Errorf("%q: expected %s, got %+v (found %v)", tt.text, tt.want, place, ok)
+		}
+	}
+
+	if place, _ := places.Lookup("Tamil Nadu"); place.Kind != "state" || place.RadiusKm < 100 {
+		t.Errorf("expected a state with a wide radius, got %+v", place)
+	}
+	if place, _ := places.Lookup("Hazaribagh"); place.RadiusKm <= 0 || place.State != "Jharkhand" {
+		t.Errorf("expected a default radius and state, got %+v", place)
+	}
+
+	if _, ok := places.Find("latest cricket scores"); ok {
+		t.Error("expected no place in a query without one")
+	}
+
+	var none *gazetteer.Gazetteer
+	if _, ok := none.Find("Mumbai"); ok {
+		t.Error("expected a nil gazetteer to find nothing")
+	}
+}
+
+func TestNewRejectsDuplicateNames(t *testing.T) {
+	_, err := gazetteer.New([]gazetteer.Place{
+		{Name: "Aurangabad", State: "Maharashtra"},
+		{Name: "Aurangabad", State: "Bihar"},
+	})
+	if err == nil {
+		t.Fatal("expected an error for a name used twice")
+	}
+}
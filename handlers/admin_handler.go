@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"inshorts-news-api/middleware"
+	"inshorts-news-api/models"
+	"inshorts-news-api/repositories"
+	"inshorts-news-api/services"
+	"inshorts-news-api/utils"
+)
+
+type AdminHandler struct {
+	adminService *services.ArticleAdminService
+	feeds        repositories.FeedStore
+}
+
+func NewAdminHandler(adminService *services.ArticleAdminService) *AdminHandler {
+	return &AdminHandler{adminService: adminService}
+}
+
+// SetFeeds lets admins see the fetch status of ingested feeds
+func (h *AdminHandler) SetFeeds(feeds repositories.FeedStore) {
+	h.feeds = feeds
+}
+
+// POST /api/v1/admin/articles
+func (h *AdminHandler) CreateArticle(c *gin.Context) {
+	var input models.ArticleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	article, err := h.adminService.CreateArticle(c.Request.Context(), c.GetString(middleware.AdminActorKey), input)
+	if err != nil {
+		respondAdminError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"article": models.NewAdminArticle(*article)})
+}
+
+// GET /api/v1/admin/articles/:id
+func (h *AdminHandler) GetArticle(c *gin.Context) {
+	article, err := h.adminService.GetArticle(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondAdminError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"article": models.NewAdminArticle(*article)})
+}
+
+// PATCH /api/v1/admin/articles/:id
+func (h *AdminHandler) UpdateArticle(c *gin.Context) {
+	var input models.ArticleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	article, err := h.adminService.UpdateArticle(c.Request.Context(), c.GetString(middleware.AdminActorKey), c.Param("id"), input)
+	if err != nil {
+		respondAdminError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"article": models.NewAdminArticle(*article)})
+}
+
+// DELETE /api/v1/admin/articles/:id
+func (h *AdminHandler) DeleteArticle(c *gin.Context) {
+	if err := h.adminService.DeleteArticle(c.Request.Context(), c.GetString(middleware.AdminActorKey), c.Param("id")); err != nil {
+		respondAdminError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// POST /api/v1/admin/articles/:id/restore
+func (h *AdminHandler) RestoreArticle(c *gin.Context) {
+	article, err := h.adminService.RestoreArticle(c.Request.Context(), c.GetString(middleware.AdminActorKey), c.Param("id"))
+	if err != nil {
+		respondAdminError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"article": models.NewAdminArticle(*article)})
+}
+
+// GET /api/v1/admin/feeds
+func (h *AdminHandler) ListFeeds(c *gin.Context) {
+	statuses := []models.FeedStatus{}
+	if h.feeds != nil {
+		var err error
+		if statuses, err = h.feeds.ListStatuses(c.Request.Context()); err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"feeds": statuses, "count": len(statuses)})
+}
+
+func respondAdminError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrArticleNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, "Article not found")
+	case services.IsValidationError(err):
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+	}
+}
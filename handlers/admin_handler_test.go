@@ -0,0 +1,225 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"inshorts-news-api/models"
+)
+
+func (s *testServer) doAdmin(t *testing.T, method, target, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+testAdminToken)
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	return rec
+}
+
+func decodeAdminArticle(t *testing.T, rec *httptest.ResponseRecorder, status int) models.AdminArticle {
+	t.Helper()
+
+	if rec.Code != status {
+		t.Fatalf("expected status %d, got %d: %s", status, rec.Code, rec.Body.String())
+	}
+
+	var body struct {
+		Article models.AdminArticle `json:"article"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	return body.Article
+}
+
+func TestAdminRequiresToken(t *testing.T) {
+	server := newTestServer(t, article("a", "Story", time.Now()))
+
+	if rec := server.do(t, http.MethodGet, "/api/v1/admin/articles/a", ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status 401 without token, got %d", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/articles/a", nil)
+	req.Header.Set("Authorization", "Bearer wrong")
+	rec := httptest.NewRecorder()
+	server.router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status 401 with a wrong token, got %d", rec.Code)
+	}
+}
+
+func TestAdminArticleLifecycle(t *testing.T) {
+	server := newTestServer(t)
+
+	created := decodeAdminArticle(t, server.doAdmin(t, http.MethodPost, "/api/v1/admin/articles", `{
+		"title": "Metro line opens",
+		"url": "https://example.com/metro",
+		"source_name": "Test Wire",
+		"category": ["national", "National", "Infra_2025"],
+		"relevance_score": 0.8,
+		"latitude": 12.97,
+		"longitude": 77.59
+	}`), http.StatusCreated)
+	if created.ID == "" || len(created.Category) != 2 {
+		t.Fatalf("unexpected created article: %+v", created)
+	}
+	// Created articles are embedded straight away for semantic search
+	if page := decodePage(t, server.do(t, http.MethodGet, "/api/v1/news/semantic?query=metro", "")); len(page.Articles) != 1 || page.Articles[0].URL != created.URL {
+		t.Fatalf("expected the created article in semantic search, got %+v", page.Articles)
+	}
+
+	updated := decodeAdminArticle(t, server.doAdmin(t, http.MethodPatch, "/api/v1/admin/articles/"+created.ID,
+		`{"title": "Metro line opens early"}`), http.StatusOK)
+	if updated.Title != "Metro line opens early" || updated.URL != created.URL {
+		t.Fatalf("unexpected updated article: %+v", updated)
+	}
+
+	if rec := server.doAdmin(t, http.MethodDelete, "/api/v1/admin/articles/"+created.ID, ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected status 204, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if page := decodePage(t, server.do(t, http.MethodGet, "/api/v1/news/category?category=national", "")); len(page.Articles) != 0 {
+		t.Fatalf("deleted article still listed: %+v", page.Articles)
+	}
+
+	deleted := decodeAdminArticle(t, server.doAdmin(t, http.MethodGet, "/api/v1/admin/articles/"+created.ID, ""), http.StatusOK)
+	if deleted.DeletedAt == nil {
+		t.Fatal("expected deleted_at to be set")
+	}
+
+	restored := decodeAdminArticle(t, server.doAdmin(t, http.MethodPost, "/api/v1/admin/articles/"+created.ID+"/restore", ""), http.StatusOK)
+	if restored.DeletedAt != nil {
+		t.Fatal("expected deleted_at to be cleared")
+	}
+
+	logs := server.store.AuditLogs()
+	wantActions := []string{models.AuditActionCreate, models.AuditActionUpdate, models.AuditActionDelete, models.AuditActionRestore}
+	if len(logs) != len(wantActions) {
+		t.Fatalf("expected %d audit entries, got %+v", len(wantActions), logs)
+	}
+	for i, entry := range logs {
+		if entry.Action != wantActions[i] || entry.Actor != "tester" || entry.ArticleID != created.ID {
+			t.Errorf("audit entry %d: unexpected %+v", i, entry)
+		}
+	}
+	if !strings.Contains(logs[1].Changes, `"title":{"old":"Metro line opens","new":"Metro line opens early"}`) {
+		t.Errorf("expected title diff in update entry, got %s", logs[1].Changes)
+	}
+}
+
+func TestAdminValidatesArticles(t *testing.T) {
+	server := newTestServer(t, article("a", "Story", time.Now(), "technology"))
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		status int
+	}{
+		{"relative url", http.MethodPost, "/api/v1/admin/articles",
+			`{"title": "t", "url": "/news/1", "source_name": "s", "category": ["world"]}`, http.StatusBadRequest},
+		{"latitude out of range", http.MethodPost, "/api/v1/admin/articles",
+			`{"title": "t", "url": "https://example.com/1", "source_name": "s", "category": ["world"], "latitude": 91}`, http.StatusBadRequest},
+		{"invalid category", http.MethodPatch, "/api/v1/admin/articles/a", `{"category": ["world news!"]}`, http.StatusBadRequest},
+		{"missing category", http.MethodPatch, "/api/v1/admin/articles/a", `{"category": []}`, http.StatusBadRequest},
+		{"unknown article", http.MethodPatch, "/api/v1/admin/articles/missing", `{"title": "t"}`, http.StatusNotFound},
+		{"restore live article", http.MethodPost, "/api/v1/admin/articles/a/restore", "", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := server.doAdmin(t, tt.method, tt.target, tt.body)
+			if rec.Code != tt.status {
+				t.Fatalf("expected status %d, got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+		})
+	}
+
+	if logs := server.store.AuditLogs(); len(logs) != 0 {
+		t.Fatalf("rejected requests must not be audited, got %+v", logs)
+	}
+}
+
+func TestAdminListsFeedStatuses(t *testing.T) {
+	server := newTestServer(t)
+	fetched := time.Now().UTC().Truncate(time.Second)
+	for _, status := range []models.FeedStatus{
+		{FeedURL: "https://example.com/world.xml", Name: "World", LastFetchedAt: &fetched, LastStatusCode: http.StatusOK, ItemsInserted: 3},
+		{FeedURL: "https://example.com/broken.xml", Name: "Broken", LastError: "unexpected status 500", ConsecutiveFailures: 2},
+	} {
+		if err := server.feeds.SaveStatus(context.Background(), &status); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if rec := server.do(t, http.MethodGet, "/api/v1/admin/feeds", ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status 401 without a token, got %d", rec.Code)
+	}
+
+	rec := server.doAdmin(t, http.MethodGet, "/api/v1/admin/feeds", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var body struct {
+		Feeds []models.FeedStatus `json:"feeds"`
+		Count int                 `json:"count"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Count != 2 || body.Feeds[0].Name != "Broken" || body.Feeds[0].ConsecutiveFailures != 2 ||
+		body.Feeds[1].ItemsInserted != 3 || !body.Feeds[1].LastFetchedAt.Equal(fetched) {
+		t.Fatalf("unexpected feed statuses: %+v", body)
+	}
+}
+
+func TestAdminUpdateReassignsStory(t *testing.T) {
+	server := newTestServer(t)
+
+	create := func(title, description string) models.AdminArticle {
+		t.Helper()
+		body, _ := json.Marshal(map[string]interface{}{
+			"title":       title,
+			"description": description,
+			"url":         "https://example.com/" + strings.ReplaceAll(strings.ToLower(title), " ", "-"),
+			"source_name": "Test Wire",
+			"category":    []string{"national"},
+		})
+		return decodeAdminArticle(t, server.doAdmin(t, http.MethodPost, "/api/v1/admin/articles", string(body)), http.StatusCreated)
+	}
+	metro := create("Metro purple line opens in Bengaluru",
+		"The new purple metro line opens to commuters in Bengaluru on Monday morning after years of delays")
+	budget := create("Parliament passes the union budget",
+		"Lawmakers approve the union budget after a long debate over spending on rural roads and schools")
+	if metro.StoryID != metro.ID || budget.StoryID != budget.ID {
+		t.Fatalf("expected separate stories, got %q and %q", metro.StoryID, budget.StoryID)
+	}
+
+	// Rewritten to cover the metro opening, the article joins that story
+	rewritten := decodeAdminArticle(t, server.doAdmin(t, http.MethodPatch, "/api/v1/admin/articles/"+budget.ID, `{
+		"title": "Metro purple line opens in Bengaluru today",
+		"description": "The new purple metro line opens to commuters in Bengaluru on Monday morning after years of delays"
+	}`), http.StatusOK)
+	if rewritten.StoryID != metro.ID {
+		t.Fatalf("expected the rewritten article in story %s, got %q", metro.ID, rewritten.StoryID)
+	}
+
+	// Changing it back starts its own story again
+	reverted := decodeAdminArticle(t, server.doAdmin(t, http.MethodPatch, "/api/v1/admin/articles/"+budget.ID, `{
+		"title": "Parliament passes the union budget",
+		"description": "Lawmakers approve the union budget after a long debate over spending on rural roads and schools"
+	}`), http.StatusOK)
+	if reverted.StoryID != budget.ID {
+		t.Fatalf("expected the reverted article in its own story, got %q", reverted.StoryID)
+	}
+}
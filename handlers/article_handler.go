@@ -1,25 +1,34 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 
+	"inshorts-news-api/gazetteer"
 	"inshorts-news-api/models"
 	"inshorts-news-api/services"
 	"inshorts-news-api/utils"
 )
 
+// maxTrendingHoursBack bounds the trending window, which is aggregated from
+// raw events whenever it has not been precomputed
+const maxTrendingHoursBack = 7 * 24
+
 type ArticleHandler struct {
 	articleService *services.ArticleService
 	llmService     *services.LLMService
+	places         *gazetteer.Gazetteer
 }
 
-func NewArticleHandler(articleService *services.ArticleService, llmService *services.LLMService) *ArticleHandler {
+func NewArticleHandler(articleService *services.ArticleService, llmService *services.LLMService, places *gazetteer.Gazetteer) *ArticleHandler {
 	return &ArticleHandler{
 		articleService: articleService,
 		llmService:     llmService,
+		places:         places,
 	}
 }
 
@@ -32,53 +41,92 @@ func (h *ArticleHandler) QueryNews(c *gin.Context) {
 	}
 
 	location := c.Query("location")
-	lat, _ := strconv.ParseFloat(c.Query("lat"), 64)
-	lon, _ := strconv.ParseFloat(c.Query("lon"), 64)
-	radius, _ := strconv.ParseFloat(c.DefaultQuery("radius", "50"), 64)
+	radius, err := strconv.ParseFloat(c.DefaultQuery("radius", "50"), 64)
+	if err != nil || radius <= 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid radius")
+		return
+	}
+
+	// Only use a position the client actually sent
+	var origin *models.GeoFilter
+	if c.Query("lat") != "" || c.Query("lon") != "" {
+		lat, err := strconv.ParseFloat(c.Query("lat"), 64)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid latitude")
+			return
+		}
+		lon, err := strconv.ParseFloat(c.Query("lon"), 64)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid longitude")
+			return
+		}
+		origin = &models.GeoFilter{Lat: lat, Lon: lon, RadiusKm: radius}
+	} else if location != "" {
+		// Without coordinates, location may still name a known place
+		origin = services.ResolveLocation(h.places, location)
+		if origin != nil && c.Query("radius") != "" {
+			origin.RadiusKm = radius
+		}
+	}
+
+	page, err := parsePageRequest(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
 
 	// Analyze query using LLM
-	intent, err := h.llmService.AnalyzeQuery(query, location)
+	intent, err := h.llmService.AnalyzeQuery(c.Request.Context(), query, location)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to analyze query: "+err.Error())
 		return
 	}
 
-	// Build parameters based on intent
-	params := make(map[string]interface{})
-	params["query"] = query
-
-	switch intent.Intent {
-	case "category":
-		if len(intent.Entities) > 0 {
-			params["category"] = intent.Entities[0]
-		} else {
-			params["category"] = "General"
-		}
-	case "source":
-		if len(intent.Entities) > 0 {
-			params["source"] = intent.Entities[0]
-		}
-	case "score":
-		params["min_score"] = 0.7
-	case "nearby":
-		params["lat"] = lat
-		params["lon"] = lon
-		params["radius"] = radius
+	// Combine everything the analysis found into one filtered query
+	filter := services.PlanQuery(intent, query, origin, h.places)
+	if err := filter.Validate(); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	articles, err := h.articleService.GetArticlesByIntent(intent, params)
+	result, err := h.articleService.QueryArticles(c.Request.Context(), filter, page)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch articles: "+err.Error())
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"intent":   intent,
-		"articles": articles,
-		"count":    len(articles),
+		"intent":      intent,
+		"near":        filter.Near,
+		"articles":    result.Articles,
+		"count":       len(result.Articles),
+		"next_cursor": result.NextCursor,
 	})
 }
 
+// GET /api/v1/news
+func (h *ArticleHandler) ListArticles(c *gin.Context) {
+	filter, err := parseArticleFilter(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	page, err := parsePageRequest(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, err := h.articleService.QueryArticles(c.Request.Context(), filter, page)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
 // GET /api/v1/news/category
 func (h *ArticleHandler) GetByCategory(c *gin.Context) {
 	category := c.Query("category")
@@ -87,16 +135,22 @@ func (h *ArticleHandler) GetByCategory(c *gin.Context) {
 		return
 	}
 
+	page, err := parsePageRequest(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	intent := &models.QueryIntent{Intent: "category"}
 	params := map[string]interface{}{"category": category}
 
-	articles, err := h.articleService.GetArticlesByIntent(intent, params)
+	result, err := h.articleService.GetArticlesByIntent(c.Request.Context(), intent, params, page)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"articles": articles})
+	c.JSON(http.StatusOK, result)
 }
 
 // GET /api/v1/news/source
@@ -107,32 +161,44 @@ func (h *ArticleHandler) GetBySource(c *gin.Context) {
 		return
 	}
 
+	page, err := parsePageRequest(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	intent := &models.QueryIntent{Intent: "source"}
 	params := map[string]interface{}{"source": source}
 
-	articles, err := h.articleService.GetArticlesByIntent(intent, params)
+	result, err := h.articleService.GetArticlesByIntent(c.Request.Context(), intent, params, page)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"articles": articles})
+	c.JSON(http.StatusOK, result)
 }
 
 // GET /api/v1/news/score
 func (h *ArticleHandler) GetByScore(c *gin.Context) {
 	minScore, _ := strconv.ParseFloat(c.DefaultQuery("min_score", "0.7"), 64)
 
+	page, err := parsePageRequest(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	intent := &models.QueryIntent{Intent: "score"}
 	params := map[string]interface{}{"min_score": minScore}
 
-	articles, err := h.articleService.GetArticlesByIntent(intent, params)
+	result, err := h.articleService.GetArticlesByIntent(c.Request.Context(), intent, params, page)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"articles": articles})
+	c.JSON(http.StatusOK, result)
 }
 
 // GET /api/v1/news/search
@@ -143,20 +209,75 @@ func (h *ArticleHandler) Search(c *gin.Context) {
 		return
 	}
 
-	intent := &models.QueryIntent{Intent: "search"}
-	params := map[string]interface{}{"query": query}
+	page, err := parsePageRequest(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
 
-	articles, err := h.articleService.GetArticlesByIntent(intent, params)
+	var result *models.ArticlePage
+	switch mode := c.DefaultQuery("mode", models.SearchKeyword); mode {
+	case models.SearchKeyword:
+		intent := &models.QueryIntent{Intent: "search"}
+		params := map[string]interface{}{"query": query}
+		result, err = h.articleService.GetArticlesByIntent(c.Request.Context(), intent, params, page)
+	case models.SearchSemantic:
+		result, err = h.articleService.SemanticSearch(c.Request.Context(), query, page)
+	case models.SearchHybrid:
+		result, err = h.articleService.HybridSearch(c.Request.Context(), query, page)
+	default:
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid mode, expected keyword, semantic or hybrid")
+		return
+	}
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		respondSearchError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GET /api/v1/news/semantic
+func (h *ArticleHandler) SemanticSearch(c *gin.Context) {
+	query := c.Query("query")
+	if query == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Query parameter 'query' is required")
+		return
+	}
+
+	page, err := parsePageRequest(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, err := h.articleService.SemanticSearch(c.Request.Context(), query, page)
+	if err != nil {
+		respondSearchError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"articles": articles})
+	c.JSON(http.StatusOK, result)
+}
+
+// respondSearchError reports a missing embedding model as unavailable
+// rather than as a server fault
+func respondSearchError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrLLMUnavailable) {
+		utils.ErrorResponse(c, http.StatusServiceUnavailable, "Semantic search needs an LLM provider")
+		return
+	}
+	utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
 }
 
 // GET /api/v1/news/nearby
 func (h *ArticleHandler) GetNearby(c *gin.Context) {
+	geoJSON, err := wantsGeoJSON(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	lat, err := strconv.ParseFloat(c.Query("lat"), 64)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid latitude")
@@ -171,6 +292,12 @@ func (h *ArticleHandler) GetNearby(c *gin.Context) {
 
 	radius, _ := strconv.ParseFloat(c.DefaultQuery("radius", "10"), 64)
 
+	page, err := parsePageRequest(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	intent := &models.QueryIntent{Intent: "nearby"}
 	params := map[string]interface{}{
 		"lat":    lat,
@@ -178,17 +305,79 @@ func (h *ArticleHandler) GetNearby(c *gin.Context) {
 		"radius": radius,
 	}
 
-	articles, err := h.articleService.GetArticlesByIntent(intent, params)
+	result, err := h.articleService.GetArticlesByIntent(c.Request.Context(), intent, params, page)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if geoJSON {
+		collection := models.NewFeatureCollection(result.Articles)
+		collection.NextCursor = result.NextCursor
+		respondGeoJSON(c, collection)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GET /api/v1/news/within, and POST with the area in the body
+func (h *ArticleHandler) GetWithin(c *gin.Context) {
+	geoJSON, err := wantsGeoJSON(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var area models.GeoArea
+	var zoom *int
+
+	if c.Request.Method == http.MethodPost {
+		var req withinRequest
+		if err = c.ShouldBindJSON(&req); err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		area, zoom, err = req.area()
+	} else {
+		area, zoom, err = parseWithinQuery(c)
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	page, err := parsePageRequest(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, err := h.articleService.GetWithin(c.Request.Context(), area, zoom, page)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"articles": articles})
+	if geoJSON {
+		collection := models.NewFeatureCollection(result.Articles)
+		collection.NextCursor = result.NextCursor
+		collection.Clusters = result.Clusters
+		respondGeoJSON(c, collection)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
 }
 
 // GET /api/v1/news/trending
 func (h *ArticleHandler) GetTrending(c *gin.Context) {
+	geoJSON, err := wantsGeoJSON(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	lat, err := strconv.ParseFloat(c.Query("lat"), 64)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid latitude")
@@ -202,37 +391,64 @@ func (h *ArticleHandler) GetTrending(c *gin.Context) {
 	}
 
 	radius, _ := strconv.ParseFloat(c.DefaultQuery("radius", "50"), 64)
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "5"))
-	hoursBack, _ := strconv.Atoi(c.DefaultQuery("hours_back", "24"))
 
-	articles, err := h.articleService.GetTrending(lat, lon, radius, limit, hoursBack)
-	if err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+	// Like page_size, an oversized limit is capped rather than rejected
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "5"))
+	if err != nil || limit < 1 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid limit")
 		return
 	}
+	limit = min(limit, maxPageSize)
 
-	c.JSON(http.StatusOK, gin.H{"articles": articles})
-}
+	hoursBack, err := strconv.Atoi(c.DefaultQuery("hours_back", "24"))
+	if err != nil || hoursBack < 1 || hoursBack > maxTrendingHoursBack {
+		utils.ErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("hours_back must be between 1 and %d", maxTrendingHoursBack))
+		return
+	}
 
-// POST /api/v1/events
-func (h *ArticleHandler) RecordEvent(c *gin.Context) {
-	var req struct {
-		ArticleID string  `json:"article_id" binding:"required"`
-		EventType string  `json:"event_type" binding:"required"`
-		Latitude  float64 `json:"latitude"`
-		Longitude float64 `json:"longitude"`
+	var uniqueUsers *bool
+	if raw := c.Query("unique_users"); raw != "" {
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid unique_users")
+			return
+		}
+		uniqueUsers = &parsed
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+	result, err := h.articleService.GetTrending(c.Request.Context(), lat, lon, radius, limit, hoursBack, c.Query("algorithm"), uniqueUsers)
+	if err != nil {
+		if services.IsValidationError(err) {
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if geoJSON {
+		respondGeoJSON(c, models.NewFeatureCollection(result.Articles))
 		return
 	}
 
-	err := h.articleService.RecordUserEvent(req.ArticleID, req.EventType, req.Latitude, req.Longitude)
+	c.JSON(http.StatusOK, result)
+}
+
+// GET /api/v1/news/story/:id
+func (h *ArticleHandler) GetStory(c *gin.Context) {
+	articles, err := h.articleService.GetStory(c.Request.Context(), c.Param("id"))
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
+	if len(articles) == 0 {
+		utils.ErrorResponse(c, http.StatusNotFound, "Story not found")
+		return
+	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "Event recorded successfully"})
+	c.JSON(http.StatusOK, gin.H{
+		"story_id": c.Param("id"),
+		"articles": articles,
+		"count":    len(articles),
+	})
 }
@@ -0,0 +1,1081 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"inshorts-news-api/clustering"
+	"inshorts-news-api/eventbus"
+	"inshorts-news-api/fraud"
+	"inshorts-news-api/gazetteer"
+	"inshorts-news-api/handlers"
+	"inshorts-news-api/middleware"
+	"inshorts-news-api/models"
+	"inshorts-news-api/repositories"
+	"inshorts-news-api/routes"
+	"inshorts-news-api/services"
+	"inshorts-news-api/vectorindex"
+)
+
+const testAdminToken = "test-admin-token"
+
+type testServer struct {
+	router     *gin.Engine
+	store      *repositories.MemoryArticleStore
+	feeds      *repositories.MemoryFeedStore
+	embeddings *services.EmbeddingService
+	llmCalls   *int64
+}
+
+func newTestServer(t *testing.T, articles ...models.Article) *testServer {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	store := repositories.NewMemoryArticleStore()
+	if err := store.BulkCreate(context.Background(), articles); err != nil {
+		t.Fatalf("seeding articles: %v", err)
+	}
+
+	var llmCalls int64
+	provider := services.NewOfflineProvider()
+	provider.Reply = func(req services.ChatRequest) string {
+		atomic.AddInt64(&llmCalls, 1)
+		return "offline summary"
+	}
+
+	llmService := services.NewLLMService(provider, services.SummaryOptions{Concurrency: 2})
+	summaryService := services.NewSummaryService(repositories.NewMemorySummaryStore(), llmService)
+	embeddingService := services.NewEmbeddingService(repositories.NewMemoryEmbeddingStore(), llmService, vectorindex.New(vectorindex.Options{}))
+	articleService := services.NewArticleService(store, summaryService, embeddingService, models.TrendingOptions{})
+
+	eventService := services.NewEventService(store, models.EventOptions{ExtraTypes: []string{"bookmark"}})
+	eventService.SetFilter(fraud.NewFilter(fraud.Options{RateLimit: 5, RateWindow: time.Minute, ViewSession: 30 * time.Minute}))
+
+	adminService := services.NewArticleAdminService(store, clustering.NewClusterer(store, clustering.Options{}))
+	adminService.SetEmbeddings(embeddingService)
+	feeds := repositories.NewMemoryFeedStore()
+	adminHandler := handlers.NewAdminHandler(adminService)
+	adminHandler.SetFeeds(feeds)
+
+	router := gin.New()
+	routes.SetupRoutes(router,
+		handlers.NewArticleHandler(articleService, llmService, gazetteer.Bundled()),
+		handlers.NewEventHandler(eventService),
+		adminHandler,
+		middleware.AdminAuth(map[string]string{testAdminToken: "tester"}))
+
+	return &testServer{router: router, store: store, feeds: feeds, embeddings: embeddingService, llmCalls: &llmCalls}
+}
+
+func (s *testServer) do(t *testing.T, method, target, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	return rec
+}
+
+func decodePage(t *testing.T, rec *httptest.ResponseRecorder) models.ArticlePage {
+	t.Helper()
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var page models.ArticlePage
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	return page
+}
+
+func article(id, title string, published time.Time, categories ...string) models.Article {
+	return models.Article{
+		ID:              id,
+		Title:           title,
+		Description:     title + " description",
+		URL:             "https://example.com/" + id,
+		PublicationDate: published,
+		SourceName:      "Test Wire",
+		Category:        categories,
+		RelevanceScore:  0.5,
+	}
+}
+
+func TestGetByCategoryPaginatesWithCursor(t *testing.T) {
+	base := time.Date(2025, 3, 26, 0, 0, 0, 0, time.UTC)
+	var articles []models.Article
+	for i := 0; i < 7; i++ {
+		articles = append(articles, article(fmt.Sprintf("tech-%d", i), "Chip news", base.Add(time.Duration(i)*time.Hour), "technology"))
+	}
+	articles = append(articles, article("sport-0", "Match report", base, "sports"))
+
+	server := newTestServer(t, articles...)
+
+	var seen []string
+	target := "/api/v1/news/category?category=technology&page_size=3"
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatal("pagination did not terminate")
+		}
+
+		page := decodePage(t, server.do(t, http.MethodGet, target, ""))
+		for _, a := range page.Articles {
+			seen = append(seen, a.URL)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		target = "/api/v1/news/category?category=technology&page_size=3&cursor=" + url.QueryEscape(page.NextCursor)
+	}
+
+	if len(seen) != 7 {
+		t.Fatalf("expected 7 articles across pages, got %d: %v", len(seen), seen)
+	}
+	for i, u := range seen {
+		want := fmt.Sprintf("https://example.com/tech-%d", 6-i)
+		if u != want {
+			t.Errorf("article %d: expected %s, got %s", i, want, u)
+		}
+	}
+}
+
+func TestListingRejectsBadParameters(t *testing.T) {
+	server := newTestServer(t)
+
+	tests := []struct {
+		name   string
+		target string
+	}{
+		{"missing category", "/api/v1/news/category"},
+		{"invalid cursor", "/api/v1/news/category?category=technology&cursor=not-a-cursor"},
+		{"invalid page size", "/api/v1/news/score?page_size=0"},
+		{"invalid latitude", "/api/v1/news/nearby?lat=north&lon=77"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := server.do(t, http.MethodGet, tt.target, "")
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("expected status 400, got %d: %s", rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestSearchPagesThroughTiedRanks(t *testing.T) {
+	now := time.Now()
+	var articles []models.Article
+	for i := 0; i < 5; i++ {
+		articles = append(articles, article(fmt.Sprintf("tie-%d", i), "Monsoon floods", now))
+	}
+	articles = append(articles, article("best", "Monsoon floods: monsoon floods monsoon", now))
+	server := newTestServer(t, articles...)
+
+	for _, base := range []string{"/api/v1/news/search?page_size=2&query=monsoon", "/api/v1/news?page_size=2&q=monsoon"} {
+		seen := map[string]bool{}
+		target := base
+		for pages := 0; ; pages++ {
+			if pages > len(articles) {
+				t.Fatalf("%s: pagination did not terminate", base)
+			}
+			page := decodePage(t, server.do(t, http.MethodGet, target, ""))
+			for _, a := range page.Articles {
+				if seen[a.URL] {
+					t.Fatalf("%s: %s repeated across pages", base, a.URL)
+				}
+				seen[a.URL] = true
+			}
+			if page.NextCursor == "" {
+				break
+			}
+			target = base + "&cursor=" + url.QueryEscape(page.NextCursor)
+		}
+		if len(seen) != len(articles) {
+			t.Fatalf("%s: expected all %d articles across pages, got %v", base, len(articles), seen)
+		}
+	}
+}
+
+func TestSearchSupportsPhrasesAndExclusions(t *testing.T) {
+	now := time.Now()
+	server := newTestServer(t,
+		article("a", "Delhi elections results announced", now),
+		article("b", "Elections in Mumbai delayed", now),
+		article("c", "Results of the cricket final", now),
+	)
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{`elections`, []string{"https://example.com/a", "https://example.com/b"}},
+		{`elections -mumbai`, []string{"https://example.com/a"}},
+		{`"elections results"`, []string{"https://example.com/a"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			page := decodePage(t, server.do(t, http.MethodGet, "/api/v1/news/search?query="+url.QueryEscape(tt.query), ""))
+
+			got := map[string]bool{}
+			for _, a := range page.Articles {
+				got[a.URL] = true
+				if a.TextRank <= 0 || !strings.Contains(a.Snippet, "<mark>") {
+					t.Errorf("expected rank and highlighted snippet for %s, got %v %q", a.URL, a.TextRank, a.Snippet)
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+			for _, u := range tt.want {
+				if !got[u] {
+					t.Errorf("expected %s in results, got %v", u, got)
+				}
+			}
+		})
+	}
+}
+
+func TestGetNearbyOrdersByDistance(t *testing.T) {
+	now := time.Now()
+	far := article("far", "Far away", now)
+	far.Latitude, far.Longitude = 28.70, 77.10 // Delhi
+	near := article("near", "Close by", now)
+	near.Latitude, near.Longitude = 12.98, 77.60
+	nearest := article("nearest", "Right here", now)
+	nearest.Latitude, nearest.Longitude = 12.97, 77.59
+
+	server := newTestServer(t, far, near, nearest)
+
+	page := decodePage(t, server.do(t, http.MethodGet, "/api/v1/news/nearby?lat=12.97&lon=77.59&radius=20", ""))
+	if len(page.Articles) != 2 {
+		t.Fatalf("expected 2 articles within 20km, got %d", len(page.Articles))
+	}
+	if page.Articles[0].URL != "https://example.com/nearest" || page.Articles[1].URL != "https://example.com/near" {
+		t.Errorf("unexpected order: %s, %s", page.Articles[0].URL, page.Articles[1].URL)
+	}
+	if d := page.Articles[0].DistanceKm; d == nil || *d != 0 {
+		t.Errorf("expected distance 0 for the article at the origin, got %v", d)
+	}
+	if d := page.Articles[1].DistanceKm; d == nil || *d < 1 || *d > 2 {
+		t.Errorf("expected a distance of about 1.5km, got %v", d)
+	}
+}
+
+func TestTrendingRanksByRecentInteractions(t *testing.T) {
+	now := time.Now()
+	quiet := article("quiet", "Quiet story", now)
+	popular := article("popular", "Popular story", now.Add(-time.Hour))
+	for _, a := range []*models.Article{&quiet, &popular} {
+		a.Latitude, a.Longitude = 19.07, 72.87
+	}
+
+	server := newTestServer(t, quiet, popular)
+	for _, eventType := range []string{"share", "click", "view"} {
+		err := server.store.CreateUserEvent(context.Background(), &models.UserEvent{
+			ArticleID: "popular",
+			EventType: eventType,
+			Timestamp: now.Add(-10 * time.Minute),
+		})
+		if err != nil {
+			t.Fatalf("recording event: %v", err)
+		}
+	}
+
+	page := decodePage(t, server.do(t, http.MethodGet, "/api/v1/news/trending?lat=19.07&lon=72.87&radius=10", ""))
+	if len(page.Articles) != 2 || page.Articles[0].URL != "https://example.com/popular" {
+		t.Fatalf("expected popular story first, got %+v", page.Articles)
+	}
+
+	popularRank, quietRank := page.Articles[0], page.Articles[1]
+	if popularRank.InteractionCount == nil || *popularRank.InteractionCount != 3 || popularRank.TrendingScore == nil || *popularRank.TrendingScore <= 0 {
+		t.Errorf("expected 3 interactions and a positive score, got %v %v", popularRank.InteractionCount, popularRank.TrendingScore)
+	}
+	if quietRank.InteractionCount == nil || *quietRank.InteractionCount != 0 || quietRank.TrendingScore == nil || *quietRank.TrendingScore != 0 {
+		t.Errorf("expected zero interactions and score, got %v %v", quietRank.InteractionCount, quietRank.TrendingScore)
+	}
+	if popularRank.DistanceKm == nil {
+		t.Error("expected trending articles to carry their distance")
+	}
+}
+
+func TestListArticlesCombinesFilters(t *testing.T) {
+	now := time.Now()
+
+	match := article("match", "New chip launched", now.Add(-2*time.Hour), "technology")
+	match.SourceName = "Reuters"
+	match.Latitude, match.Longitude = 28.61, 77.20
+
+	stale := match
+	stale.ID, stale.URL = "stale", "https://example.com/stale"
+	stale.PublicationDate = now.Add(-72 * time.Hour)
+
+	otherSource := match
+	otherSource.ID, otherSource.URL = "other-source", "https://example.com/other-source"
+	otherSource.SourceName = "News18"
+
+	farAway := match
+	farAway.ID, farAway.URL = "far-away", "https://example.com/far-away"
+	farAway.Latitude, farAway.Longitude = 12.97, 77.59
+
+	otherCategory := match
+	otherCategory.ID, otherCategory.URL = "other-category", "https://example.com/other-category"
+	otherCategory.Category = []string{"sports"}
+
+	server := newTestServer(t, match, stale, otherSource, farAway, otherCategory)
+
+	page := decodePage(t, server.do(t, http.MethodGet,
+		"/api/v1/news?category=Technology,business&source=reuters&since=48h&lat=28.6&lon=77.2&radius=100", ""))
+	if len(page.Articles) != 1 || page.Articles[0].URL != "https://example.com/match" {
+		t.Fatalf("expected only the matching article, got %+v", page.Articles)
+	}
+
+	rec := server.do(t, http.MethodGet, "/api/v1/news?sort=distance", "")
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 for distance sort without location, got %d", rec.Code)
+	}
+}
+
+func TestRecordEvent(t *testing.T) {
+	server := newTestServer(t, article("a", "Story", time.Now()))
+
+	rec := server.do(t, http.MethodPost, "/api/v1/events", `{"article_id": "a", "event_type": "view"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	events := server.store.Events()
+	if len(events) != 1 || events[0].ArticleID != "a" || events[0].EventType != "view" {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+
+	if events[0].Timestamp.IsZero() || time.Since(events[0].Timestamp) > time.Minute {
+		t.Fatalf("expected a server timestamp, got %v", events[0].Timestamp)
+	}
+
+	rec = server.do(t, http.MethodPost, "/api/v1/events", `{"event_type": "view"}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 without article_id, got %d", rec.Code)
+	}
+}
+
+func TestEventValidation(t *testing.T) {
+	deleted := article("gone", "Deleted story", time.Now())
+	deleted.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	server := newTestServer(t, article("a", "Story", time.Now()), deleted)
+
+	single := []struct {
+		body   string
+		status int
+	}{
+		{`{"article_id": "a", "event_type": "bookmark"}`, http.StatusCreated},
+		{`{"article_id": "a", "event_type": "like"}`, http.StatusBadRequest},
+		{`{"article_id": "missing", "event_type": "view"}`, http.StatusNotFound},
+		{`{"article_id": "gone", "event_type": "view"}`, http.StatusNotFound},
+	}
+	for _, tc := range single {
+		if rec := server.do(t, http.MethodPost, "/api/v1/events", tc.body); rec.Code != tc.status {
+			t.Errorf("%s: expected status %d, got %d: %s", tc.body, tc.status, rec.Code, rec.Body.String())
+		}
+	}
+
+	now := time.Now()
+	stamp := func(d time.Duration) string { return now.Add(d).UTC().Format(time.RFC3339) }
+	rec := server.do(t, http.MethodPost, "/api/v1/events/batch", fmt.Sprintf(`{"events": [
+		{"article_id": "a", "event_type": "view", "timestamp": %q},
+		{"article_id": "a", "event_type": "view", "timestamp": %q},
+		{"article_id": "a", "event_type": "view", "timestamp": %q},
+		{"article_id": "gone", "event_type": "view"},
+		{"article_id": "a", "event_type": "like"}
+	]}`, stamp(2*time.Minute), stamp(time.Hour), stamp(-100*time.Hour)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Results []models.EventResult `json:"results"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	expected := []string{
+		"",
+		"timestamp is more than 5m0s in the future",
+		"timestamp is more than 72h0m0s old",
+		"article not found",
+		`unknown event_type "like"`,
+	}
+	for i, result := range resp.Results {
+		if result.Error != expected[i] {
+			t.Errorf("event %d: expected error %q, got %+v", i, expected[i], result)
+		}
+	}
+
+	// A client clock slightly ahead is clamped to the server time
+	events := server.store.Events()
+	if len(events) != 2 || events[1].Timestamp.After(time.Now()) {
+		t.Fatalf("expected the skewed timestamp to be clamped, got %+v", events)
+	}
+
+	rec = server.do(t, http.MethodGet, "/api/v1/events/types", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `["bookmark","click","share","view"]`) {
+		t.Fatalf("expected the registered event types, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestRecordEventBatch(t *testing.T) {
+	server := newTestServer(t, article("a", "Story", time.Now()))
+
+	type batchResponse struct {
+		Accepted   int                  `json:"accepted"`
+		Duplicates int                  `json:"duplicates"`
+		Rejected   int                  `json:"rejected"`
+		Results    []models.EventResult `json:"results"`
+	}
+	send := func(body string) batchResponse {
+		t.Helper()
+		rec := server.do(t, http.MethodPost, "/api/v1/events/batch", body)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp batchResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decoding response: %v", err)
+		}
+		return resp
+	}
+
+	viewedAt := time.Now().Add(-time.Hour).Truncate(time.Second).UTC()
+	resp := send(`{"events": [
+		{"article_id": "a", "event_type": "view", "timestamp": "` + viewedAt.Format(time.RFC3339) + `", "idempotency_key": "k1"},
+		{"article_id": "a", "event_type": "click", "idempotency_key": "k1"},
+		{"event_type": "view"},
+		{"article_id": "a", "event_type": "share", "latitude": 120},
+		{"article_id": "a", "event_type": "share"}
+	]}`)
+	if resp.Accepted != 2 || resp.Duplicates != 1 || resp.Rejected != 2 {
+		t.Fatalf("expected 2 accepted, 1 duplicate and 2 rejected, got %+v", resp)
+	}
+	expected := []string{models.EventAccepted, models.EventDuplicate, models.EventRejected, models.EventRejected, models.EventAccepted}
+	for i, result := range resp.Results {
+		if result.Index != i || result.Status != expected[i] {
+			t.Fatalf("result %d: expected %s, got %+v", i, expected[i], result)
+		}
+	}
+	if resp.Results[2].Error != "article_id is required" {
+		t.Fatalf("expected the rejection reason, got %q", resp.Results[2].Error)
+	}
+
+	events := server.store.Events()
+	if len(events) != 2 || !events[0].Timestamp.Equal(viewedAt) || events[1].Timestamp.IsZero() {
+		t.Fatalf("expected the client timestamp and a server one, got %+v", events)
+	}
+
+	// A retried batch stores nothing twice. The retry is a click, since a
+	// retried view would collapse into the first view's session instead.
+	resp = send(`{"events": [{"article_id": "a", "event_type": "click", "idempotency_key": "k1"}]}`)
+	if resp.Duplicates != 1 || len(server.store.Events()) != 2 {
+		t.Fatalf("expected the retry to be a duplicate, got %+v", resp)
+	}
+
+	tooMany := strings.Repeat(`{"article_id": "a", "event_type": "view"},`, services.MaxEventBatchSize)
+	rec := server.do(t, http.MethodPost, "/api/v1/events/batch", `{"events": [`+tooMany+`{"article_id": "a", "event_type": "view"}]}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 for an oversized batch, got %d", rec.Code)
+	}
+}
+
+func TestRecordEventBatchThroughBus(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+
+	store := repositories.NewMemoryArticleStore()
+	if err := store.BulkCreate(ctx, []models.Article{article("a", "Story", time.Now())}); err != nil {
+		t.Fatalf("seeding articles: %v", err)
+	}
+	stored := "stored"
+	if _, err := store.CreateUserEvents(ctx, []models.UserEvent{{ArticleID: "a", EventType: models.EventView, Timestamp: time.Now(), IdempotencyKey: &stored}}); err != nil {
+		t.Fatalf("seeding events: %v", err)
+	}
+
+	// Events stay buffered until the bus is closed
+	bus := eventbus.New(store, eventbus.Options{FlushInterval: time.Hour})
+	eventService := services.NewEventService(store, models.EventOptions{})
+	eventService.SetPublisher(bus)
+	router := gin.New()
+	router.POST("/events/batch", handlers.NewEventHandler(eventService).RecordEventBatch)
+
+	send := func(keys ...string) []string {
+		t.Helper()
+		items := make([]string, len(keys))
+		for i, key := range keys {
+			items[i] = `{"article_id": "a", "event_type": "view", "idempotency_key": "` + key + `"}`
+		}
+		req := httptest.NewRequest(http.MethodPost, "/events/batch", strings.NewReader(`{"events": [`+strings.Join(items, ",")+`]}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		var resp struct {
+			Results []models.EventResult `json:"results"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		statuses := make([]string, len(resp.Results))
+		for i, result := range resp.Results {
+			statuses[i] = result.Status
+		}
+		return statuses
+	}
+
+	// Keys already stored, buffered or repeated in the batch are duplicates
+	if got := send("k1", "stored", "k1"); !slices.Equal(got, []string{models.EventAccepted, models.EventDuplicate, models.EventDuplicate}) {
+		t.Fatalf("unexpected statuses %v", got)
+	}
+	if got := send("k1", "k2"); !slices.Equal(got, []string{models.EventDuplicate, models.EventAccepted}) {
+		t.Fatalf("unexpected statuses %v", got)
+	}
+
+	if err := bus.Close(ctx); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	if events := store.Events(); len(events) != 3 {
+		t.Fatalf("expected the seeded event and 2 buffered ones stored, got %d", len(events))
+	}
+}
+
+func TestEventAbuseFiltering(t *testing.T) {
+	located := article("a", "Story", time.Now())
+	located.Latitude, located.Longitude = 19.07, 72.87
+	server := newTestServer(t, located)
+
+	send := func(userAgent, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/events", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", userAgent)
+		rec := httptest.NewRecorder()
+		server.router.ServeHTTP(rec, req)
+		return rec
+	}
+	const app = "NewsApp/3.1 (Android 14)"
+
+	rec := send("Mozilla/5.0 (compatible; Googlebot/2.1)", `{"article_id": "a", "event_type": "share"}`)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"reason":"bot"`) {
+		t.Fatalf("expected the bot event to be discarded, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// Views within the session count once
+	for i, expected := range []int{http.StatusCreated, http.StatusOK} {
+		if rec := send(app, `{"article_id": "a", "event_type": "view"}`); rec.Code != expected {
+			t.Fatalf("view %d: expected status %d, got %d: %s", i, expected, rec.Code, rec.Body.String())
+		}
+	}
+
+	// Shares beyond the rate limit are kept but marked suspicious
+	for i := 0; i < 6; i++ {
+		if rec := send(app, `{"article_id": "a", "event_type": "share"}`); rec.Code != http.StatusCreated {
+			t.Fatalf("share %d: expected status 201, got %d", i, rec.Code)
+		}
+	}
+
+	events := server.store.Events()
+	if len(events) != 7 {
+		t.Fatalf("expected 1 view and 6 shares stored, got %+v", events)
+	}
+	if last := events[6]; !last.Suspicious || last.SuspiciousReason != fraud.ReasonRateLimited {
+		t.Fatalf("expected the 6th share to be suspicious, got %+v", last)
+	}
+
+	rec = server.do(t, http.MethodGet, "/api/v1/news/trending?lat=19.07&lon=72.87&radius=10", "")
+	var page models.TrendingPage
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if len(page.Articles) != 1 || *page.Articles[0].InteractionCount != 5 {
+		t.Fatalf("expected trending to count the view and 4 shares within the limit, got %s", rec.Body.String())
+	}
+}
+
+func TestUserIdentityOnEvents(t *testing.T) {
+	now := time.Now()
+	located := func(id string) models.Article {
+		a := article(id, "Story "+id, now.Add(-time.Hour))
+		a.Latitude, a.Longitude = 19.07, 72.87
+		return a
+	}
+	server := newTestServer(t, located("a"), located("b"))
+
+	rec := server.do(t, http.MethodPost, "/api/v1/events",
+		`{"article_id": "a", "event_type": "share", "user_id": "u1", "session_id": "s1", "device_id": "d1"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if events := server.store.Events(); events[0].UserID != "u1" || events[0].SessionID != "s1" || events[0].DeviceID != "d1" {
+		t.Fatalf("expected the identifiers to be stored, got %+v", events[0])
+	}
+
+	// One user shares "a" three times; three people interact with "b"
+	seed := []models.UserEvent{
+		{ArticleID: "a", EventType: "share", UserID: "u1"},
+		{ArticleID: "a", EventType: "share", UserID: "u1"},
+		{ArticleID: "b", EventType: "view", UserID: "u2"},
+		{ArticleID: "b", EventType: "view", UserID: "u3"},
+		{ArticleID: "b", EventType: "click", DeviceID: "d2"},
+	}
+	for i := range seed {
+		seed[i].Timestamp = now.Add(-10 * time.Minute)
+	}
+	if _, err := server.store.CreateUserEvents(context.Background(), seed); err != nil {
+		t.Fatalf("seeding events: %v", err)
+	}
+
+	trending := func(query string) (models.TrendingPage, []string) {
+		t.Helper()
+		rec := server.do(t, http.MethodGet, "/api/v1/news/trending?lat=19.07&lon=72.87&radius=10"+query, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var page models.TrendingPage
+		if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+			t.Fatalf("decoding response: %v", err)
+		}
+		var order []string
+		for _, a := range page.Articles {
+			order = append(order, fmt.Sprintf("%s:%d", a.Title, *a.InteractionCount))
+		}
+		return page, order
+	}
+
+	if page, order := trending(""); page.UniqueUsers || strings.Join(order, ",") != "Story a:3,Story b:3" {
+		t.Fatalf("expected raw events to favour a, got %v", order)
+	}
+	if page, order := trending("&unique_users=true"); !page.UniqueUsers || strings.Join(order, ",") != "Story b:3,Story a:1" {
+		t.Fatalf("expected unique users to favour b, got %v", order)
+	}
+	if rec := server.do(t, http.MethodGet, "/api/v1/news/trending?lat=19.07&lon=72.87&unique_users=maybe", ""); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 for an invalid unique_users, got %d", rec.Code)
+	}
+
+	if rec := server.do(t, http.MethodDelete, "/api/v1/admin/users/u1/events", ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status 401 without a token, got %d", rec.Code)
+	}
+	rec = server.doAdmin(t, http.MethodDelete, "/api/v1/admin/users/u1/events", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"deleted":3`) {
+		t.Fatalf("expected 3 events deleted, got %d: %s", rec.Code, rec.Body.String())
+	}
+	for _, event := range server.store.Events() {
+		if event.UserID == "u1" {
+			t.Fatalf("expected no events of u1 left, got %+v", event)
+		}
+	}
+}
+
+func TestSummariesAreCached(t *testing.T) {
+	server := newTestServer(t, article("a", "Story", time.Now(), "technology"))
+
+	for i := 0; i < 2; i++ {
+		page := decodePage(t, server.do(t, http.MethodGet, "/api/v1/news/category?category=technology", ""))
+		if len(page.Articles) != 1 || page.Articles[0].LLMSummary != "offline summary" {
+			t.Fatalf("unexpected articles: %+v", page.Articles)
+		}
+	}
+
+	if calls := atomic.LoadInt64(server.llmCalls); calls != 1 {
+		t.Fatalf("expected 1 LLM call thanks to the cache, got %d", calls)
+	}
+}
+
+func TestStoriesAndCollapsedListings(t *testing.T) {
+	now := time.Now()
+	first := article("first", "Metro line opens", now.Add(-2*time.Hour), "national")
+	first.StoryID = "first"
+	rewrite := article("rewrite", "Metro line opens to public", now.Add(-time.Hour), "national")
+	rewrite.StoryID = "first"
+	other := article("other", "Budget passed", now.Add(-30*time.Minute), "national")
+	other.StoryID = "other"
+
+	server := newTestServer(t, first, rewrite, other)
+
+	rec := server.do(t, http.MethodGet, "/api/v1/news/story/first", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var story struct {
+		Articles []models.ArticleResponse `json:"articles"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &story); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if len(story.Articles) != 2 || story.Articles[0].URL != "https://example.com/first" {
+		t.Fatalf("expected both articles of the story oldest first, got %+v", story.Articles)
+	}
+
+	if rec := server.do(t, http.MethodGet, "/api/v1/news/story/missing", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404 for an unknown story, got %d", rec.Code)
+	}
+
+	page := decodePage(t, server.do(t, http.MethodGet, "/api/v1/news/category?category=national", ""))
+	if len(page.Articles) != 3 {
+		t.Fatalf("expected 3 articles without collapsing, got %d", len(page.Articles))
+	}
+
+	page = decodePage(t, server.do(t, http.MethodGet, "/api/v1/news/category?category=national&collapse=true", ""))
+	if len(page.Articles) != 2 {
+		t.Fatalf("expected 2 articles when collapsed, got %+v", page.Articles)
+	}
+	if page.Articles[1].URL != "https://example.com/rewrite" || page.Articles[1].Duplicates != 1 {
+		t.Errorf("expected the newest article of the story to stand in for it, got %+v", page.Articles[1])
+	}
+
+	// A story does not come back on a later page
+	var urls []string
+	cursor := ""
+	for {
+		page := decodePage(t, server.do(t, http.MethodGet, "/api/v1/news/category?category=national&collapse=true&page_size=1&cursor="+url.QueryEscape(cursor), ""))
+		for _, article := range page.Articles {
+			urls = append(urls, article.URL)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+	if len(urls) != 2 || urls[0] != "https://example.com/other" || urls[1] != "https://example.com/rewrite" {
+		t.Fatalf("expected each story once across pages, got %v", urls)
+	}
+}
+
+func TestSemanticAndHybridSearch(t *testing.T) {
+	now := time.Now()
+	articles := []models.Article{
+		article("rain", "Monsoon rain floods Kerala villages", now),
+		article("cricket", "India wins the cricket final", now),
+		article("budget", "Parliament passes the union budget", now),
+	}
+	server := newTestServer(t, articles...)
+
+	if n, err := server.embeddings.EmbedArticles(context.Background(), articles); err != nil || n != 3 {
+		t.Fatalf("expected 3 embeddings, got %d: %v", n, err)
+	}
+	// Unchanged articles are not embedded again
+	if n, _ := server.embeddings.EmbedArticles(context.Background(), articles); n != 0 {
+		t.Fatalf("expected no new embeddings, got %d", n)
+	}
+
+	for _, target := range []string{
+		"/api/v1/news/semantic?query=" + url.QueryEscape("kerala monsoon floods"),
+		"/api/v1/news/search?mode=semantic&query=" + url.QueryEscape("kerala monsoon floods"),
+		"/api/v1/news/search?mode=hybrid&query=" + url.QueryEscape("kerala monsoon floods"),
+	} {
+		page := decodePage(t, server.do(t, http.MethodGet, target, ""))
+		if len(page.Articles) == 0 || page.Articles[0].URL != "https://example.com/rain" {
+			t.Fatalf("%s: expected the monsoon article first, got %+v", target, page.Articles)
+		}
+		if page.Articles[0].Score <= 0 {
+			t.Errorf("%s: expected a score, got %v", target, page.Articles[0].Score)
+		}
+	}
+
+	// Scored results page with the same cursors as other listings
+	first := decodePage(t, server.do(t, http.MethodGet, "/api/v1/news/semantic?page_size=1&query=monsoon", ""))
+	if len(first.Articles) != 1 || first.NextCursor == "" {
+		t.Fatalf("expected one article and a cursor, got %+v", first)
+	}
+	second := decodePage(t, server.do(t, http.MethodGet, "/api/v1/news/semantic?page_size=1&query=monsoon&cursor="+first.NextCursor, ""))
+	if len(second.Articles) != 1 || second.Articles[0].URL == first.Articles[0].URL {
+		t.Fatalf("expected a different second article, got %+v", second.Articles)
+	}
+
+	if rec := server.do(t, http.MethodGet, "/api/v1/news/search?mode=fuzzy&query=monsoon", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown mode, got %d", rec.Code)
+	}
+}
+
+func TestWithinBoxAndPolygonWithClusters(t *testing.T) {
+	now := time.Now()
+	located := func(id string, lat, lon float64, published time.Time) models.Article {
+		a := article(id, "Local news "+id, published)
+		a.Latitude, a.Longitude = lat, lon
+		return a
+	}
+	server := newTestServer(t,
+		located("mg-road", 12.975, 77.605, now),
+		located("indiranagar", 12.978, 77.640, now.Add(-time.Hour)),
+		located("whitefield", 12.970, 77.750, now.Add(-2*time.Hour)),
+		located("mysuru", 12.300, 76.650, now.Add(-3*time.Hour)),
+	)
+
+	target := "/api/v1/news/within?bbox=77.5,12.9,77.8,13.1&zoom=9"
+	rec := server.do(t, http.MethodGet, target, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var page models.WithinPage
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if len(page.Articles) != 3 || page.Articles[0].URL != "https://example.com/mg-road" {
+		t.Fatalf("expected the 3 Bengaluru articles newest first, got %+v", page.Articles)
+	}
+
+	// Cells are about 0.18 degrees wide at zoom 9: MG Road and Indiranagar
+	// share one, Whitefield has its own
+	if len(page.Clusters) != 2 || page.Clusters[0].Count != 2 || page.Clusters[1].Count != 1 {
+		t.Fatalf("expected clusters of 2 and 1, got %+v", page.Clusters)
+	}
+	if c := page.Clusters[0]; !c.Bounds.Contains(12.975, 77.605) || c.Latitude != (12.975+12.978)/2 {
+		t.Errorf("unexpected cluster %+v", c)
+	}
+
+	// A polygon around central Bengaluru with a hole cut around Indiranagar
+	body := `{"polygon": {"type": "Feature", "geometry": {"type": "Polygon", "coordinates": [
+		[[77.55, 12.9], [77.7, 12.9], [77.7, 13.05], [77.55, 13.05], [77.55, 12.9]],
+		[[77.63, 12.97], [77.65, 12.97], [77.65, 12.99], [77.63, 12.99], [77.63, 12.97]]
+	]}}}`
+	rec = server.do(t, http.MethodPost, "/api/v1/news/within", body)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	page = models.WithinPage{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if len(page.Articles) != 1 || page.Articles[0].URL != "https://example.com/mg-road" || page.Clusters != nil {
+		t.Fatalf("expected only MG Road and no clusters, got %+v", page)
+	}
+
+	for _, bad := range []string{
+		"/api/v1/news/within",
+		"/api/v1/news/within?bbox=77.8,12.9,77.5,13.1",
+		"/api/v1/news/within?bbox=77.5,12.9,77.8",
+		"/api/v1/news/within?bbox=77.5,12.9,77.8,13.1&zoom=40",
+		"/api/v1/news/within?polygon=" + url.QueryEscape(`{"type":"Point","coordinates":[77.5,12.9]}`),
+	} {
+		if rec := server.do(t, http.MethodGet, bad, ""); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", bad, rec.Code)
+		}
+	}
+}
+
+func TestGeoEndpointsRenderGeoJSON(t *testing.T) {
+	now := time.Now()
+	a := article("here", "Right here", now)
+	a.Latitude, a.Longitude = 12.97, 77.59
+	server := newTestServer(t, a)
+
+	geoJSONRequest := func(target, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		rec := httptest.NewRecorder()
+		server.router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for _, tt := range []struct {
+		target, accept string
+	}{
+		{"/api/v1/news/nearby?lat=12.97&lon=77.59&radius=5", "application/geo+json"},
+		{"/api/v1/news/nearby?lat=12.97&lon=77.59&radius=5&format=geojson", ""},
+		{"/api/v1/news/trending?lat=12.97&lon=77.59&radius=5&format=geojson", "application/json"},
+		{"/api/v1/news/within?bbox=77,12,78,13&zoom=5", "application/geo+json, application/json;q=0.5"},
+	} {
+		rec := geoJSONRequest(tt.target, tt.accept)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d: %s", tt.target, rec.Code, rec.Body.String())
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "application/geo+json" {
+			t.Errorf("%s: expected a GeoJSON content type, got %q", tt.target, ct)
+		}
+
+		var collection models.FeatureCollection
+		if err := json.Unmarshal(rec.Body.Bytes(), &collection); err != nil {
+			t.Fatalf("%s: decoding response: %v", tt.target, err)
+		}
+		if collection.Type != "FeatureCollection" || len(collection.Features) != 1 {
+			t.Fatalf("%s: unexpected collection %+v", tt.target, collection)
+		}
+		feature := collection.Features[0]
+		if feature.Geometry.Type != "Point" || feature.Geometry.Coordinates != [2]float64{77.59, 12.97} {
+			t.Errorf("%s: expected a [lon, lat] point, got %+v", tt.target, feature.Geometry)
+		}
+		if feature.Properties.URL != "https://example.com/here" {
+			t.Errorf("%s: expected the article as properties, got %+v", tt.target, feature.Properties)
+		}
+	}
+
+	// Plain JSON stays the default, and an explicit format wins over Accept
+	for _, tt := range []struct {
+		target, accept string
+	}{
+		{"/api/v1/news/nearby?lat=12.97&lon=77.59&radius=5", ""},
+		{"/api/v1/news/nearby?lat=12.97&lon=77.59&radius=5&format=json", "application/geo+json"},
+	} {
+		rec := geoJSONRequest(tt.target, tt.accept)
+		if !strings.HasPrefix(rec.Header().Get("Content-Type"), "application/json") {
+			t.Errorf("%s: expected plain JSON, got %q", tt.target, rec.Header().Get("Content-Type"))
+		}
+	}
+
+	if rec := geoJSONRequest("/api/v1/news/nearby?lat=12.97&lon=77.59&format=kml", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown format, got %d", rec.Code)
+	}
+}
+
+func TestQueryResolvesPlaceNames(t *testing.T) {
+	now := time.Now()
+	hazaribagh := article("hazaribagh", "Coal mine expansion approved", now)
+	hazaribagh.Latitude, hazaribagh.Longitude = 23.99, 85.36
+	ranchi := article("ranchi", "Coal mine expansion in the capital", now)
+	ranchi.Latitude, ranchi.Longitude = 23.34, 85.31
+	server := newTestServer(t, hazaribagh, ranchi)
+
+	decode := func(target string) (models.GeoFilter, []models.ArticleResponse) {
+		t.Helper()
+		rec := server.do(t, http.MethodGet, target, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d: %s", target, rec.Code, rec.Body.String())
+		}
+		var body struct {
+			Near     *models.GeoFilter        `json:"near"`
+			Articles []models.ArticleResponse `json:"articles"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("decoding response: %v", err)
+		}
+		if body.Near == nil {
+			t.Fatalf("%s: expected a resolved location", target)
+		}
+		return *body.Near, body.Articles
+	}
+
+	near, articles := decode("/api/v1/news/query?q=" + url.QueryEscape("news near Hazaribagh"))
+	if near.Place != "Hazaribagh" || len(articles) != 1 || articles[0].URL != "https://example.com/hazaribagh" {
+		t.Fatalf("expected only the Hazaribagh article, got %+v %+v", near, articles)
+	}
+
+	// The location parameter stands in for coordinates, with the radius of
+	// the request when it has one
+	near, articles = decode("/api/v1/news/query?location=Ranchi&radius=100&q=" + url.QueryEscape("nearby news"))
+	if near.Place != "Ranchi" || near.RadiusKm != 100 || len(articles) != 2 || articles[0].URL != "https://example.com/ranchi" {
+		t.Fatalf("expected both articles around Ranchi, got %+v %+v", near, articles)
+	}
+
+	for _, target := range []string{
+		"/api/v1/news/query?q=floods&lat=23.99&lon=85.36&radius=-5",
+		"/api/v1/news/query?q=floods&location=Ranchi&radius=abc",
+		"/api/v1/news/query?q=floods&radius=0",
+		"/api/v1/news?lat=23.99&lon=85.36&radius=-5",
+		"/api/v1/news?lat=23.99&lon=85.36&radius=abc",
+	} {
+		if rec := server.do(t, http.MethodGet, target, ""); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d: %s", target, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestTrendingAlgorithms(t *testing.T) {
+	now := time.Now()
+	located := func(id string, lat, lon float64) models.Article {
+		a := article(id, "Story "+id, now.Add(-48*time.Hour))
+		a.Latitude, a.Longitude = lat, lon
+		return a
+	}
+	// Mumbai: "burst" was shared heavily yesterday and viewed once just now;
+	// "fresh" was clicked a few times in the last minutes
+	// Pune: "steady" is viewed as often as in the window before, "rising"
+	// only started getting views
+	server := newTestServer(t,
+		located("burst", 19.07, 72.87), located("fresh", 19.07, 72.87),
+		located("steady", 18.52, 73.86), located("rising", 18.52, 73.86))
+
+	record := func(articleID, eventType string, count int, ago time.Duration) {
+		for i := 0; i < count; i++ {
+			err := server.store.CreateUserEvent(context.Background(), &models.UserEvent{
+				ArticleID: articleID,
+				EventType: eventType,
+				Timestamp: now.Add(-ago),
+			})
+			if err != nil {
+				t.Fatalf("recording event: %v", err)
+			}
+		}
+	}
+	record("burst", "share", 5, 20*time.Hour)
+	record("burst", "view", 1, 5*time.Minute)
+	record("fresh", "click", 3, 10*time.Minute)
+	record("steady", "view", 10, 30*time.Hour)
+	record("steady", "view", 10, time.Hour)
+	record("rising", "view", 2, 5*time.Minute)
+
+	trending := func(query string) models.TrendingPage {
+		t.Helper()
+		rec := server.do(t, http.MethodGet, "/api/v1/news/trending?radius=10&hours_back=24&"+query, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d: %s", query, rec.Code, rec.Body.String())
+		}
+		var page models.TrendingPage
+		if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+			t.Fatalf("decoding response: %v", err)
+		}
+		if len(page.Articles) != 2 {
+			t.Fatalf("%s: expected 2 articles, got %+v", query, page.Articles)
+		}
+		return page
+	}
+
+	tests := []struct {
+		query     string
+		algorithm string
+		first     string
+	}{
+		{"lat=19.07&lon=72.87", models.TrendingClassic, "burst"},
+		{"lat=19.07&lon=72.87&algorithm=decay", models.TrendingDecay, "fresh"},
+		{"lat=18.52&lon=73.86", models.TrendingClassic, "steady"},
+		{"lat=18.52&lon=73.86&algorithm=velocity", models.TrendingVelocity, "rising"},
+	}
+	for _, tt := range tests {
+		page := trending(tt.query)
+		if page.Algorithm != tt.algorithm || page.Articles[0].URL != "https://example.com/"+tt.first {
+			t.Errorf("%s: expected %s first by %s, got %s by %s", tt.query, tt.first, tt.algorithm, page.Articles[0].URL, page.Algorithm)
+		}
+	}
+
+	// Velocity only counts the current window's events as interactions
+	page := trending("lat=18.52&lon=73.86&algorithm=velocity")
+	if steady := page.Articles[1]; *steady.InteractionCount != 10 || *steady.TrendingScore != 0 {
+		t.Errorf("expected 10 interactions and no growth, got %v %v", *steady.InteractionCount, *steady.TrendingScore)
+	}
+
+	if rec := server.do(t, http.MethodGet, "/api/v1/news/trending?lat=18.52&lon=73.86&algorithm=random", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown algorithm, got %d", rec.Code)
+	}
+	for _, query := range []string{"limit=0", "limit=-3", "limit=five", "hours_back=0", "hours_back=169", "hours_back=day"} {
+		if rec := server.do(t, http.MethodGet, "/api/v1/news/trending?lat=18.52&lon=73.86&"+query, ""); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, rec.Code)
+		}
+	}
+	// An oversized limit is capped like page_size
+	if rec := server.do(t, http.MethodGet, "/api/v1/news/trending?lat=18.52&lon=73.86&limit=1000&hours_back=168", ""); rec.Code != http.StatusOK {
+		t.Errorf("expected 200 for a large limit, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
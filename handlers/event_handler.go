@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"inshorts-news-api/eventbus"
+	"inshorts-news-api/middleware"
+	"inshorts-news-api/models"
+	"inshorts-news-api/repositories"
+	"inshorts-news-api/services"
+	"inshorts-news-api/utils"
+)
+
+type EventHandler struct {
+	eventService *services.EventService
+}
+
+func NewEventHandler(eventService *services.EventService) *EventHandler {
+	return &EventHandler{eventService: eventService}
+}
+
+// GET /api/v1/events/types
+func (h *EventHandler) ListEventTypes(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"event_types": h.eventService.EventTypes()})
+}
+
+// POST /api/v1/events
+func (h *EventHandler) RecordEvent(c *gin.Context) {
+	var req struct {
+		ArticleID string  `json:"article_id" binding:"required"`
+		EventType string  `json:"event_type" binding:"required"`
+		Latitude  float64 `json:"latitude"`
+		Longitude float64 `json:"longitude"`
+		UserID    string  `json:"user_id"`
+		SessionID string  `json:"session_id"`
+		DeviceID  string  `json:"device_id"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	discarded, err := h.eventService.RecordUserEvent(c.Request.Context(), eventClient(c), models.UserEvent{
+		ArticleID: req.ArticleID,
+		EventType: req.EventType,
+		Latitude:  req.Latitude,
+		Longitude: req.Longitude,
+		UserID:    req.UserID,
+		SessionID: req.SessionID,
+		DeviceID:  req.DeviceID,
+	})
+	switch {
+	case errors.Is(err, repositories.ErrArticleNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, "Article not found")
+		return
+	case services.IsValidationError(err):
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	case isEventBusUnavailable(err):
+		respondEventBusUnavailable(c)
+		return
+	case err != nil:
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Discarded events still succeed, so clients do not retry them
+	if discarded != "" {
+		c.JSON(http.StatusOK, gin.H{"message": "Event discarded", "reason": discarded})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Event recorded successfully"})
+}
+
+// eventBatchItem is one event of a batch, as buffered by a client.
+// Timestamp is when the event happened on the client.
+type eventBatchItem struct {
+	ArticleID      string     `json:"article_id"`
+	EventType      string     `json:"event_type"`
+	Latitude       float64    `json:"latitude"`
+	Longitude      float64    `json:"longitude"`
+	Timestamp      *time.Time `json:"timestamp"`
+	IdempotencyKey *string    `json:"idempotency_key"`
+	UserID         string     `json:"user_id"`
+	SessionID      string     `json:"session_id"`
+	DeviceID       string     `json:"device_id"`
+}
+
+// POST /api/v1/events/batch
+// Invalid events do not fail the batch; each is reported in results, at
+// its index in the request, as accepted, duplicate or rejected.
+func (h *EventHandler) RecordEventBatch(c *gin.Context) {
+	var req struct {
+		Events []eventBatchItem `json:"events" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	events := make([]models.UserEvent, len(req.Events))
+	for i, item := range req.Events {
+		events[i] = models.UserEvent{
+			ArticleID:      item.ArticleID,
+			EventType:      item.EventType,
+			Latitude:       item.Latitude,
+			Longitude:      item.Longitude,
+			IdempotencyKey: item.IdempotencyKey,
+			UserID:         item.UserID,
+			SessionID:      item.SessionID,
+			DeviceID:       item.DeviceID,
+		}
+		if item.Timestamp != nil {
+			events[i].Timestamp = *item.Timestamp
+		}
+	}
+
+	results, err := h.eventService.RecordUserEvents(c.Request.Context(), eventClient(c), events)
+	if err != nil {
+		if services.IsValidationError(err) {
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		if isEventBusUnavailable(err) {
+			respondEventBusUnavailable(c)
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	counts := make(map[string]int)
+	for _, result := range results {
+		counts[result.Status]++
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"accepted":   counts[models.EventAccepted],
+		"duplicates": counts[models.EventDuplicate],
+		"rejected":   counts[models.EventRejected],
+		"discarded":  counts[models.EventDiscarded],
+		"results":    results,
+	})
+}
+
+// DELETE /api/v1/admin/users/:id/events
+func (h *EventHandler) DeleteUserEvents(c *gin.Context) {
+	deleted, err := h.eventService.DeleteUserEvents(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if services.IsValidationError(err) {
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	log.Printf("Admin %s deleted %d events of user %s", c.GetString(middleware.AdminActorKey), deleted, c.Param("id"))
+	c.JSON(http.StatusOK, gin.H{"user_id": c.Param("id"), "deleted": deleted})
+}
+
+func eventClient(c *gin.Context) models.EventClient {
+	return models.EventClient{IP: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+}
+
+// isEventBusUnavailable reports whether events were refused because the
+// buffer is full or shutting down, which clients should retry
+func isEventBusUnavailable(err error) bool {
+	return errors.Is(err, eventbus.ErrFull) || errors.Is(err, eventbus.ErrClosed)
+}
+
+func respondEventBusUnavailable(c *gin.Context) {
+	c.Header("Retry-After", "1")
+	utils.ErrorResponse(c, http.StatusServiceUnavailable, "Too many events, retry shortly")
+}
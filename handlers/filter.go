@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"inshorts-news-api/models"
+)
+
+// parseArticleFilter reads the filters of GET /api/v1/news. Every parameter
+// is optional; category and source may repeat or hold comma-separated values.
+func parseArticleFilter(c *gin.Context) (models.ArticleFilter, error) {
+	filter := models.ArticleFilter{
+		Categories: queryList(c, "category"),
+		Sources:    queryList(c, "source"),
+		Text:       strings.TrimSpace(c.Query("q")),
+	}
+
+	var err error
+	if filter.MinScore, err = optionalFloat(c, "min_score"); err != nil {
+		return filter, err
+	}
+	if filter.MaxScore, err = optionalFloat(c, "max_score"); err != nil {
+		return filter, err
+	}
+
+	if filter.From, err = optionalTime(c, "from", false); err != nil {
+		return filter, err
+	}
+	if filter.To, err = optionalTime(c, "to", true); err != nil {
+		return filter, err
+	}
+
+	// since=48h is shorthand for a from date relative to now
+	if since := c.Query("since"); since != "" {
+		d, err := time.ParseDuration(since)
+		if err != nil || d <= 0 {
+			return filter, errors.New("Invalid since, expected a duration such as 48h")
+		}
+		from := time.Now().Add(-d)
+		filter.From = &from
+	}
+
+	if c.Query("lat") != "" || c.Query("lon") != "" {
+		lat, err := strconv.ParseFloat(c.Query("lat"), 64)
+		if err != nil || lat < -90 || lat > 90 {
+			return filter, errors.New("Invalid latitude")
+		}
+		lon, err := strconv.ParseFloat(c.Query("lon"), 64)
+		if err != nil || lon < -180 || lon > 180 {
+			return filter, errors.New("Invalid longitude")
+		}
+		radius, err := strconv.ParseFloat(c.DefaultQuery("radius", "50"), 64)
+		if err != nil {
+			return filter, errors.New("Invalid radius")
+		}
+		filter.Near = &models.GeoFilter{Lat: lat, Lon: lon, RadiusKm: radius}
+	}
+
+	filter.Sort = c.Query("sort")
+	if filter.Sort == "" {
+		switch {
+		case filter.Text != "":
+			filter.Sort = models.SortByTextRank
+		case filter.Near != nil:
+			filter.Sort = models.SortByDistance
+		default:
+			filter.Sort = models.SortByDate
+		}
+	}
+
+	return filter, filter.Validate()
+}
+
+func queryList(c *gin.Context, key string) []string {
+	var values []string
+	for _, raw := range c.QueryArray(key) {
+		for _, v := range strings.Split(raw, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+	}
+	return values
+}
+
+func optionalFloat(c *gin.Context, key string) (*float64, error) {
+	raw := c.Query(key)
+	if raw == "" {
+		return nil, nil
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return nil, errors.New("Invalid " + key)
+	}
+	return &v, nil
+}
+
+// optionalTime accepts RFC3339 or a bare date; a bare end date covers the whole day
+func optionalTime(c *gin.Context, key string, endOfDay bool) (*time.Time, error) {
+	raw := c.Query(key)
+	if raw == "" {
+		return nil, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return &t, nil
+	}
+
+	t, err := time.Parse("2006-01-02", raw)
+	if err != nil {
+		return nil, errors.New("Invalid " + key + ", expected RFC3339 or YYYY-MM-DD")
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return &t, nil
+}
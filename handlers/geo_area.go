@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"inshorts-news-api/models"
+)
+
+// withinRequest is the body of POST /api/v1/news/within, for polygons too
+// large to fit in a URL. BBox is [min_lon, min_lat, max_lon, max_lat] as in
+// GeoJSON.
+type withinRequest struct {
+	BBox    []float64       `json:"bbox"`
+	Polygon json.RawMessage `json:"polygon"`
+	Zoom    *int            `json:"zoom"`
+}
+
+// parseWithinQuery reads bbox, polygon and zoom from the query string
+func parseWithinQuery(c *gin.Context) (models.GeoArea, *int, error) {
+	req := withinRequest{}
+
+	if raw := c.Query("bbox"); raw != "" {
+		for _, part := range strings.Split(raw, ",") {
+			v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+			if err != nil {
+				return models.GeoArea{}, nil, errors.New("Invalid bbox")
+			}
+			req.BBox = append(req.BBox, v)
+		}
+	}
+
+	if raw := c.Query("polygon"); raw != "" {
+		req.Polygon = json.RawMessage(raw)
+	}
+
+	if raw := c.Query("zoom"); raw != "" {
+		zoom, err := strconv.Atoi(raw)
+		if err != nil {
+			return models.GeoArea{}, nil, errors.New("Invalid zoom")
+		}
+		req.Zoom = &zoom
+	}
+
+	return req.area()
+}
+
+func (req withinRequest) area() (models.GeoArea, *int, error) {
+	var area models.GeoArea
+
+	if req.BBox != nil {
+		if len(req.BBox) != 4 {
+			return area, nil, errors.New("Invalid bbox, expected min_lon,min_lat,max_lon,max_lat")
+		}
+		area.Box = &models.BoundingBox{
+			MinLon: req.BBox[0],
+			MinLat: req.BBox[1],
+			MaxLon: req.BBox[2],
+			MaxLat: req.BBox[3],
+		}
+	}
+
+	if len(req.Polygon) > 0 && string(req.Polygon) != "null" {
+		polygon, err := models.ParseGeoJSONPolygon(req.Polygon)
+		if err != nil {
+			return area, nil, errors.New("Invalid polygon: " + err.Error())
+		}
+		area.Polygon = polygon
+	}
+
+	if err := area.Validate(); err != nil {
+		return area, nil, err
+	}
+
+	if req.Zoom != nil && (*req.Zoom < 0 || *req.Zoom > models.MaxZoom) {
+		return area, nil, errors.New("Invalid zoom, expected 0 to " + strconv.Itoa(models.MaxZoom))
+	}
+
+	return area, req.Zoom, nil
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"inshorts-news-api/models"
+)
+
+const geoJSONMediaType = "application/geo+json"
+
+// wantsGeoJSON picks the response format of a geo endpoint: format=geojson
+// or format=json when given, otherwise the Accept header
+func wantsGeoJSON(c *gin.Context) (bool, error) {
+	c.Header("Vary", "Accept")
+
+	switch c.Query("format") {
+	case "geojson":
+		return true, nil
+	case "json":
+		return false, nil
+	case "":
+		return c.NegotiateFormat(gin.MIMEJSON, geoJSONMediaType) == geoJSONMediaType, nil
+	default:
+		return false, errors.New("Invalid format, expected json or geojson")
+	}
+}
+
+func respondGeoJSON(c *gin.Context, collection models.FeatureCollection) {
+	c.Header("Content-Type", geoJSONMediaType)
+	c.JSON(http.StatusOK, collection)
+}
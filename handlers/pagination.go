@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"inshorts-news-api/models"
+	"inshorts-news-api/utils"
+)
+
+const (
+	defaultPageSize = 10
+	maxPageSize     = 100
+)
+
+// parsePageRequest reads the `cursor`, `page_size` and `collapse` query
+// parameters
+func parsePageRequest(c *gin.Context) (models.PageRequest, error) {
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(defaultPageSize)))
+	if err != nil || pageSize < 1 {
+		return models.PageRequest{}, errors.New("Invalid page_size")
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+
+	cursor, err := utils.DecodeCursor(c.Query("cursor"))
+	if err != nil {
+		return models.PageRequest{}, errors.New("Invalid cursor")
+	}
+
+	collapse, err := strconv.ParseBool(c.DefaultQuery("collapse", "false"))
+	if err != nil {
+		return models.PageRequest{}, errors.New("Invalid collapse")
+	}
+
+	return models.PageRequest{Cursor: cursor, PageSize: pageSize, Collapse: collapse}, nil
+}
@@ -0,0 +1,98 @@
+package ingestion
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"inshorts-news-api/models"
+	"inshorts-news-api/utils"
+)
+
+// defaultCategory matches what scripts/load_data.go assigns to articles
+// without categories
+const defaultCategory = "General"
+
+const (
+	maxCategoryLength    = 50
+	maxDescriptionLength = 2000
+)
+
+var categorySeparators = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// toArticle maps a feed item onto an article. Items without a title or link
+// cannot become articles and are skipped.
+func toArticle(feed FeedConfig, parsed *parsedFeed, item feedItem, fetchedAt time.Time) (models.Article, bool) {
+	if item.Title == "" || item.Link == "" {
+		return models.Article{}, false
+	}
+
+	published := item.Published
+	if published.IsZero() || published.After(fetchedAt) {
+		published = fetchedAt
+	}
+
+	description := item.Summary
+	if len(description) > maxDescriptionLength {
+		description = strings.ToValidUTF8(description[:maxDescriptionLength], "") + "..."
+	}
+
+	return models.Article{
+		ID:              utils.IDFromURL(item.Link),
+		Title:           item.Title,
+		Description:     description,
+		URL:             item.Link,
+		PublicationDate: published,
+		SourceName:      sourceName(feed, parsed, item),
+		Category:        pq.StringArray(categories(feed, item)),
+		RelevanceScore:  feed.relevanceScore(),
+		Latitude:        feed.Latitude,
+		Longitude:       feed.Longitude,
+	}, true
+}
+
+// sourceName prefers the configured name, then the item's own source, the
+// feed title and finally the feed's host
+func sourceName(feed FeedConfig, parsed *parsedFeed, item feedItem) string {
+	switch {
+	case feed.Name != "":
+		return feed.Name
+	case item.Source != "":
+		return item.Source
+	case parsed.Title != "":
+		return parsed.Title
+	}
+
+	if u, err := url.Parse(feed.URL); err == nil && u.Host != "" {
+		return strings.TrimPrefix(u.Hostname(), "www.")
+	}
+	return feed.URL
+}
+
+// categories merges the item's categories with the feed's, rewriting them
+// into the single-token form the existing data uses ("Health & Fitness"
+// becomes "Health_Fitness")
+func categories(feed FeedConfig, item feedItem) []string {
+	var result []string
+	seen := make(map[string]bool)
+
+	for _, raw := range append(append([]string{}, item.Categories...), feed.Categories...) {
+		category := strings.Trim(categorySeparators.ReplaceAllString(strings.TrimSpace(raw), "_"), "_-")
+		if len(category) > maxCategoryLength {
+			category = category[:maxCategoryLength]
+		}
+		if category == "" || seen[strings.ToLower(category)] {
+			continue
+		}
+		seen[strings.ToLower(category)] = true
+		result = append(result, category)
+	}
+
+	if len(result) == 0 {
+		result = []string{defaultCategory}
+	}
+	return result
+}
@@ -0,0 +1,58 @@
+package ingestion
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+)
+
+// defaultRelevanceScore is used for feeds that do not configure one
+const defaultRelevanceScore = 0.5
+
+// FeedConfig describes one feed to ingest. Feeds carry no location or
+// relevance of their own, so both come from the configuration.
+type FeedConfig struct {
+	// Name becomes the source_name of the feed's articles. When empty the
+	// feed's own title is used.
+	Name string `json:"name"`
+	URL  string `json:"url"`
+
+	// Categories are added to the categories of every item
+	Categories     []string `json:"categories"`
+	Latitude       float64  `json:"latitude"`
+	Longitude      float64  `json:"longitude"`
+	RelevanceScore *float64 `json:"relevance_score"`
+}
+
+func (f FeedConfig) relevanceScore() float64 {
+	if f.RelevanceScore != nil {
+		return *f.RelevanceScore
+	}
+	return defaultRelevanceScore
+}
+
+// LoadFeeds reads a JSON array of feed configurations
+func LoadFeeds(path string) ([]FeedConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading feed list: %w", err)
+	}
+
+	var feeds []FeedConfig
+	if err := json.Unmarshal(data, &feeds); err != nil {
+		return nil, fmt.Errorf("parsing feed list: %w", err)
+	}
+
+	for _, feed := range feeds {
+		u, err := url.Parse(feed.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return nil, fmt.Errorf("feed %q has an invalid url", feed.URL)
+		}
+		if score := feed.relevanceScore(); score < 0 || score > 1 {
+			return nil, fmt.Errorf("feed %q: relevance_score must be between 0 and 1", feed.URL)
+		}
+	}
+
+	return feeds, nil
+}
@@ -0,0 +1,241 @@
+package ingestion
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// feedItem is an RSS item or Atom entry reduced to the fields articles use
+type feedItem struct {
+	Title      string
+	Link       string
+	Summary    string
+	Published  time.Time
+	Categories []string
+	Source     string
+}
+
+type parsedFeed struct {
+	Title string
+	Items []feedItem
+}
+
+type rssDocument struct {
+	Channel struct {
+		Title string    `xml:"title"`
+		Items []rssItem `xml:"item"`
+	} `xml:"channel"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	GUID        string   `xml:"guid"`
+	Description string   `xml:"description"`
+	PubDate     string   `xml:"pubDate"`
+	Date        string   `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Categories  []string `xml:"category"`
+	Source      string   `xml:"source"`
+}
+
+type atomDocument struct {
+	Title   string      `xml:"title"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Links      []atomLink     `xml:"link"`
+	Summary    string         `xml:"summary"`
+	Content    string         `xml:"content"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Categories []atomCategory `xml:"category"`
+	Source     struct {
+		Title string `xml:"title"`
+	} `xml:"source"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+}
+
+type atomCategory struct {
+	Term  string `xml:"term,attr"`
+	Label string `xml:"label,attr"`
+}
+
+// parseFeed reads an RSS 2.0 or Atom document, telling them apart by the
+// root element. Relative item links are resolved against base.
+func parseFeed(data []byte, base *url.URL) (*parsedFeed, error) {
+	root, err := rootElement(data)
+	if err != nil {
+		return nil, err
+	}
+
+	switch root {
+	case "rss":
+		var doc rssDocument
+		if err := xml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("parsing RSS feed: %w", err)
+		}
+		return fromRSS(doc, base), nil
+	case "feed":
+		var doc atomDocument
+		if err := xml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("parsing Atom feed: %w", err)
+		}
+		return fromAtom(doc, base), nil
+	default:
+		return nil, fmt.Errorf("unsupported feed format <%s>", root)
+	}
+}
+
+func rootElement(data []byte) (string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", fmt.Errorf("reading feed: %w", err)
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name.Local, nil
+		}
+	}
+}
+
+func fromRSS(doc rssDocument, base *url.URL) *parsedFeed {
+	feed := &parsedFeed{Title: cleanText(doc.Channel.Title)}
+
+	for _, item := range doc.Channel.Items {
+		link := strings.TrimSpace(item.Link)
+		if link == "" && strings.HasPrefix(item.GUID, "http") {
+			link = strings.TrimSpace(item.GUID)
+		}
+
+		date := item.PubDate
+		if date == "" {
+			date = item.Date
+		}
+
+		feed.Items = append(feed.Items, feedItem{
+			Title:      cleanText(item.Title),
+			Link:       resolveLink(base, link),
+			Summary:    cleanText(item.Description),
+			Published:  parseFeedDate(date),
+			Categories: item.Categories,
+			Source:     cleanText(item.Source),
+		})
+	}
+
+	return feed
+}
+
+func fromAtom(doc atomDocument, base *url.URL) *parsedFeed {
+	feed := &parsedFeed{Title: cleanText(doc.Title)}
+
+	for _, entry := range doc.Entries {
+		summary := entry.Summary
+		if summary == "" {
+			summary = entry.Content
+		}
+
+		date := entry.Published
+		if date == "" {
+			date = entry.Updated
+		}
+
+		var categories []string
+		for _, c := range entry.Categories {
+			if c.Term != "" {
+				categories = append(categories, c.Term)
+			} else {
+				categories = append(categories, c.Label)
+			}
+		}
+
+		feed.Items = append(feed.Items, feedItem{
+			Title:      cleanText(entry.Title),
+			Link:       resolveLink(base, atomEntryLink(entry)),
+			Summary:    cleanText(summary),
+			Published:  parseFeedDate(date),
+			Categories: categories,
+			Source:     cleanText(entry.Source.Title),
+		})
+	}
+
+	return feed
+}
+
+// atomEntryLink prefers the alternate link, which is what a link without
+// rel means too
+func atomEntryLink(entry atomEntry) string {
+	for _, link := range entry.Links {
+		if link.Rel == "" || link.Rel == "alternate" {
+			return strings.TrimSpace(link.Href)
+		}
+	}
+	if len(entry.Links) > 0 {
+		return strings.TrimSpace(entry.Links[0].Href)
+	}
+	if strings.HasPrefix(entry.ID, "http") {
+		return strings.TrimSpace(entry.ID)
+	}
+	return ""
+}
+
+func resolveLink(base *url.URL, link string) string {
+	if link == "" || base == nil {
+		return link
+	}
+	ref, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+	return base.ResolveReference(ref).String()
+}
+
+// feedDateLayouts covers RFC 822 dates as RSS feeds actually write them,
+// and the RFC 3339 dates of Atom and Dublin Core
+var feedDateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"Mon, 02 Jan 2006 15:04 -0700",
+	"2 Jan 2006 15:04:05 -0700",
+	time.RFC3339,
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+}
+
+// parseFeedDate returns the zero time when the date is missing or unreadable
+func parseFeedDate(value string) time.Time {
+	value = strings.TrimSpace(value)
+	for _, layout := range feedDateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+var (
+	tagPattern        = regexp.MustCompile(`<[^>]*>`)
+	whitespacePattern = regexp.MustCompile(`\s+`)
+)
+
+// cleanText strips markup and entities from feed text
+func cleanText(value string) string {
+	value = tagPattern.ReplaceAllString(value, " ")
+	value = html.UnescapeString(value)
+	return strings.TrimSpace(whitespacePattern.ReplaceAllString(value, " "))
+}
@@ -0,0 +1,247 @@
+package ingestion
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"inshorts-news-api/clustering"
+	"inshorts-news-api/models"
+	"inshorts-news-api/repositories"
+)
+
+// maxFeedSize bounds how much of a feed response is read
+const maxFeedSize = 10 << 20
+
+type Options struct {
+	// Interval between polls of all feeds
+	Interval time.Duration
+	// Timeout bounds a single feed fetch
+	Timeout time.Duration
+	// Client defaults to http.DefaultClient
+	Client *http.Client
+	// Clusterer, when set, assigns new articles to stories
+	Clusterer *clustering.Clusterer
+	// Embedder, when set, embeds new articles for semantic search
+	Embedder Embedder
+}
+
+// Embedder computes and stores article embeddings
+type Embedder interface {
+	EmbedArticles(ctx context.Context, articles []models.Article) (int, error)
+}
+
+// Worker polls RSS and Atom feeds and stores their new items as articles.
+// Fetches are conditional on the ETag and Last-Modified of the previous
+// response, and the outcome of each fetch is kept as the feed's status.
+type Worker struct {
+	feeds    []FeedConfig
+	articles repositories.ArticleStore
+	statuses repositories.FeedStore
+	options  Options
+	now      func() time.Time
+}
+
+func NewWorker(feeds []FeedConfig, articles repositories.ArticleStore, statuses repositories.FeedStore, options Options) *Worker {
+	if options.Interval <= 0 {
+		options.Interval = 15 * time.Minute
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = 20 * time.Second
+	}
+	if options.Client == nil {
+		options.Client = http.DefaultClient
+	}
+
+	return &Worker{
+		feeds:    feeds,
+		articles: articles,
+		statuses: statuses,
+		options:  options,
+		now:      time.Now,
+	}
+}
+
+// Run polls every feed straight away and then once per interval until ctx
+// is cancelled
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.options.Interval)
+	defer ticker.Stop()
+
+	for {
+		w.PollAll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PollAll fetches each feed once and returns the number of new articles.
+// A failing feed is logged and recorded in its status without stopping
+// the others.
+func (w *Worker) PollAll(ctx context.Context) int {
+	inserted := 0
+	for _, feed := range w.feeds {
+		if ctx.Err() != nil {
+			break
+		}
+
+		status, err := w.PollFeed(ctx, feed)
+		if err != nil {
+			log.Printf("Feed %s failed: %v", feed.URL, err)
+			continue
+		}
+		inserted += status.ItemsInserted
+	}
+	return inserted
+}
+
+// PollFeed fetches one feed and stores its new items. The returned status
+// has also been saved, whether or not the fetch succeeded.
+func (w *Worker) PollFeed(ctx context.Context, feed FeedConfig) (*models.FeedStatus, error) {
+	status, err := w.statuses.GetStatus(ctx, feed.URL)
+	if err != nil {
+		return nil, fmt.Errorf("loading feed status: %w", err)
+	}
+	if status == nil {
+		status = &models.FeedStatus{FeedURL: feed.URL}
+	}
+	status.Name = feed.Name
+
+	fetchedAt := w.now()
+	status.LastFetchedAt = &fetchedAt
+	status.ItemsSeen, status.ItemsInserted = 0, 0
+
+	fetchErr := w.fetch(ctx, feed, status, fetchedAt)
+	if fetchErr != nil {
+		status.LastError = fetchErr.Error()
+		status.ConsecutiveFailures++
+	} else {
+		status.LastError = ""
+		status.ConsecutiveFailures = 0
+		status.LastSuccessAt = &fetchedAt
+	}
+
+	// Record the outcome even if the fetch was cut short by ctx
+	if err := w.statuses.SaveStatus(context.WithoutCancel(ctx), status); err != nil {
+		return status, fmt.Errorf("saving feed status: %w", err)
+	}
+
+	return status, fetchErr
+}
+
+func (w *Worker) fetch(ctx context.Context, feed FeedConfig, status *models.FeedStatus, fetchedAt time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, w.options.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feed.URL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/xml;q=0.9, text/xml;q=0.8")
+	req.Header.Set("User-Agent", "inshorts-news-api/ingestion")
+	if status.ETag != "" {
+		req.Header.Set("If-None-Match", status.ETag)
+	}
+	if status.LastModified != "" {
+		req.Header.Set("If-Modified-Since", status.LastModified)
+	}
+
+	resp, err := w.options.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	status.LastStatusCode = resp.StatusCode
+	if resp.StatusCode == http.StatusNotModified {
+		return nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFeedSize))
+	if err != nil {
+		return fmt.Errorf("reading feed: %w", err)
+	}
+
+	base, _ := url.Parse(feed.URL)
+	parsed, err := parseFeed(data, base)
+	if err != nil {
+		return err
+	}
+
+	var articles []models.Article
+	for _, item := range parsed.Items {
+		if article, ok := toArticle(feed, parsed, item, fetchedAt); ok {
+			articles = append(articles, article)
+		}
+	}
+	status.ItemsSeen = len(parsed.Items)
+
+	// Feeds repeat their items on every poll, so leave out the stored ones
+	// before paying to cluster and embed them
+	articles, err = w.unstored(ctx, articles)
+	if err != nil {
+		return fmt.Errorf("checking stored articles: %w", err)
+	}
+
+	if w.options.Clusterer != nil {
+		if err := w.options.Clusterer.Assign(ctx, articles); err != nil {
+			return fmt.Errorf("clustering articles: %w", err)
+		}
+	}
+
+	inserted, err := w.articles.CreateNew(ctx, articles)
+	if err != nil {
+		return fmt.Errorf("storing articles: %w", err)
+	}
+	status.ItemsInserted = len(inserted)
+
+	// Missing embeddings are caught up by the backfill, so they do not fail
+	// the fetch
+	if w.options.Embedder != nil && len(inserted) > 0 {
+		if _, err := w.options.Embedder.EmbedArticles(ctx, inserted); err != nil {
+			log.Printf("Embedding articles from %s failed: %v", feed.URL, err)
+		}
+	}
+
+	// Only keep validators once the items behind them are stored
+	status.ETag = resp.Header.Get("ETag")
+	status.LastModified = resp.Header.Get("Last-Modified")
+
+	return nil
+}
+
+// unstored returns the articles whose URL is not stored yet
+func (w *Worker) unstored(ctx context.Context, articles []models.Article) ([]models.Article, error) {
+	urls := make([]string, len(articles))
+	for i, article := range articles {
+		urls[i] = article.URL
+	}
+
+	existing, err := w.articles.StoredURLs(ctx, urls)
+	if err != nil {
+		return nil, err
+	}
+	stored := make(map[string]bool, len(existing))
+	for _, link := range existing {
+		stored[link] = true
+	}
+
+	var fresh []models.Article
+	for _, article := range articles {
+		if !stored[article.URL] {
+			fresh = append(fresh, article)
+		}
+	}
+	return fresh, nil
+}
@@ -0,0 +1,235 @@
+package ingestion_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"inshorts-news-api/clustering"
+	"inshorts-news-api/ingestion"
+	"inshorts-news-api/models"
+	"inshorts-news-api/repositories"
+	"inshorts-news-api/utils"
+)
+
+const rssFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0">
+  <channel>
+    <title>Test Wire</title>
+    <item>
+      <title>Metro line opens in Bengaluru</title>
+      <link>https://example.com/metro</link>
+      <description><![CDATA[<p>The new line &amp; its stations</p>]]></description>
+      <pubDate>Wed, 26 Mar 2025 04:46:55 +0530</pubDate>
+      <category>Health &amp; Fitness</category>
+      <category>city</category>
+    </item>
+    <item>
+      <title>Budget session begins</title>
+      <link>/budget</link>
+      <description>Parliament meets</description>
+    </item>
+    <item>
+      <description>An item without title or link</description>
+    </item>
+  </channel>
+</rss>`
+
+const atomFeed = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Atom Times</title>
+  <entry>
+    <title>Metro line opens in Bengaluru</title>
+    <link rel="alternate" href="https://example.com/metro"/>
+    <updated>2025-03-26T05:00:00Z</updated>
+  </entry>
+  <entry>
+    <title>Monsoon arrives early</title>
+    <link rel="alternate" href="https://example.com/monsoon"/>
+    <summary>Rain across Kerala</summary>
+    <published>2025-03-25T10:00:00Z</published>
+    <category term="weather"/>
+  </entry>
+</feed>`
+
+type feedServer struct {
+	*httptest.Server
+	rssRequests      int64
+	notModifiedCount int64
+}
+
+func newFeedServer(t *testing.T) *feedServer {
+	t.Helper()
+
+	s := &feedServer{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rss", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&s.rssRequests, 1)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt64(&s.notModifiedCount, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write([]byte(rssFeed))
+	})
+	mux.HandleFunc("/atom", func(w http.ResponseWriter, r *http.Request) {
+		lastModified := "Wed, 26 Mar 2025 06:00:00 GMT"
+		if r.Header.Get("If-Modified-Since") == lastModified {
+			atomic.AddInt64(&s.notModifiedCount, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Last-Modified", lastModified)
+		w.Write([]byte(atomFeed))
+	})
+	// Serves the RSS feed without validators, so every poll gets every item
+	mux.HandleFunc("/plain", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(rssFeed))
+	})
+	mux.HandleFunc("/broken", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	})
+
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestWorkerIngestsFeeds(t *testing.T) {
+	server := newFeedServer(t)
+	articles := repositories.NewMemoryArticleStore()
+	statuses := repositories.NewMemoryFeedStore()
+	ctx := context.Background()
+
+	feeds := []ingestion.FeedConfig{
+		{URL: server.URL + "/rss", Categories: []string{"national"}, Latitude: 12.97, Longitude: 77.59},
+		{Name: "Atom Desk", URL: server.URL + "/atom"},
+		{URL: server.URL + "/broken"},
+	}
+	worker := ingestion.NewWorker(feeds, articles, statuses, ingestion.Options{Client: server.Client()})
+
+	if inserted := worker.PollAll(ctx); inserted != 3 {
+		t.Fatalf("expected 3 new articles, got %d", inserted)
+	}
+
+	metro, err := articles.GetByID(ctx, utils.IDFromURL("https://example.com/metro"), false)
+	if err != nil {
+		t.Fatalf("metro article not stored: %v", err)
+	}
+	if metro.SourceName != "Test Wire" || metro.Description != "The new line & its stations" {
+		t.Errorf("unexpected source or description: %q, %q", metro.SourceName, metro.Description)
+	}
+	if want := []string{"Health_Fitness", "city", "national"}; len(metro.Category) != 3 ||
+		metro.Category[0] != want[0] || metro.Category[1] != want[1] || metro.Category[2] != want[2] {
+		t.Errorf("expected categories %v, got %v", want, metro.Category)
+	}
+	if !metro.PublicationDate.Equal(time.Date(2025, 3, 25, 23, 16, 55, 0, time.UTC)) {
+		t.Errorf("unexpected publication date %v", metro.PublicationDate)
+	}
+	if metro.Latitude != 12.97 || metro.Longitude != 77.59 {
+		t.Errorf("expected the feed's location, got %v,%v", metro.Latitude, metro.Longitude)
+	}
+
+	if _, err := articles.GetByID(ctx, utils.IDFromURL(server.URL+"/budget"), false); err != nil {
+		t.Errorf("expected the relative link to be resolved: %v", err)
+	}
+
+	monsoon, err := articles.GetByID(ctx, utils.IDFromURL("https://example.com/monsoon"), false)
+	if err != nil {
+		t.Fatalf("monsoon article not stored: %v", err)
+	}
+	if monsoon.SourceName != "Atom Desk" || len(monsoon.Category) != 1 || monsoon.Category[0] != "weather" {
+		t.Errorf("unexpected atom article: %+v", monsoon)
+	}
+
+	broken, _ := statuses.GetStatus(ctx, server.URL+"/broken")
+	if broken == nil || broken.ConsecutiveFailures != 1 || broken.LastError == "" || broken.LastStatusCode != http.StatusInternalServerError {
+		t.Fatalf("expected the failure to be recorded, got %+v", broken)
+	}
+
+	// The second round is answered from the validators of the first
+	if inserted := worker.PollAll(ctx); inserted != 0 {
+		t.Fatalf("expected no new articles, got %d", inserted)
+	}
+	if n := atomic.LoadInt64(&server.notModifiedCount); n != 2 {
+		t.Fatalf("expected both feeds to answer 304, got %d", n)
+	}
+
+	rss, _ := statuses.GetStatus(ctx, server.URL+"/rss")
+	if rss.ETag != `"v1"` || rss.LastStatusCode != http.StatusNotModified || rss.LastSuccessAt == nil {
+		t.Errorf("unexpected rss status: %+v", rss)
+	}
+
+	broken, _ = statuses.GetStatus(ctx, server.URL+"/broken")
+	if broken.ConsecutiveFailures != 2 {
+		t.Errorf("expected 2 consecutive failures, got %d", broken.ConsecutiveFailures)
+	}
+
+	if count, _ := articles.Count(ctx); count != 3 {
+		t.Errorf("expected 3 stored articles, got %d", count)
+	}
+}
+
+// recordingEmbedder remembers the articles it was asked to embed
+type recordingEmbedder struct {
+	mu       sync.Mutex
+	articles []string
+}
+
+func (e *recordingEmbedder) EmbedArticles(ctx context.Context, articles []models.Article) (int, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, article := range articles {
+		e.articles = append(e.articles, article.URL)
+	}
+	return len(articles), nil
+}
+
+func TestWorkerOnlyProcessesNewItems(t *testing.T) {
+	server := newFeedServer(t)
+	articles := repositories.NewMemoryArticleStore()
+	statuses := repositories.NewMemoryFeedStore()
+	embedder := &recordingEmbedder{}
+	ctx := context.Background()
+
+	// One item is already stored, under another ID
+	if err := articles.Create(ctx, &models.Article{ID: "stored", URL: "https://example.com/metro", Title: "Metro"}); err != nil {
+		t.Fatal(err)
+	}
+
+	feed := ingestion.FeedConfig{URL: server.URL + "/plain"}
+	worker := ingestion.NewWorker([]ingestion.FeedConfig{feed}, articles, statuses, ingestion.Options{
+		Client:    server.Client(),
+		Clusterer: clustering.NewClusterer(articles, clustering.Options{}),
+		Embedder:  embedder,
+	})
+
+	if inserted := worker.PollAll(ctx); inserted != 1 {
+		t.Fatalf("expected 1 new article, got %d", inserted)
+	}
+	if len(embedder.articles) != 1 || embedder.articles[0] != server.URL+"/budget" {
+		t.Fatalf("expected only the new article embedded, got %v", embedder.articles)
+	}
+	budget, err := articles.GetByID(ctx, utils.IDFromURL(server.URL+"/budget"), false)
+	if err != nil || budget.StoryID == "" {
+		t.Fatalf("expected the new article stored with a story, got %+v %v", budget, err)
+	}
+
+	// Polling the unchanged feed again embeds nothing
+	if inserted := worker.PollAll(ctx); inserted != 0 {
+		t.Fatalf("expected no new articles, got %d", inserted)
+	}
+	if len(embedder.articles) != 1 {
+		t.Fatalf("expected no further embeddings, got %v", embedder.articles)
+	}
+	status, _ := statuses.GetStatus(ctx, feed.URL)
+	if status == nil || status.ItemsSeen != 3 || status.ItemsInserted != 0 {
+		t.Fatalf("unexpected feed status: %+v", status)
+	}
+}
@@ -1,16 +1,33 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"expvar"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
+	"inshorts-news-api/clustering"
 	"inshorts-news-api/config"
 	"inshorts-news-api/db"
+	"inshorts-news-api/eventbus"
+	"inshorts-news-api/fraud"
+	"inshorts-news-api/gazetteer"
 	"inshorts-news-api/handlers"
+	"inshorts-news-api/ingestion"
+	"inshorts-news-api/middleware"
+	"inshorts-news-api/models"
 	"inshorts-news-api/repositories"
 	"inshorts-news-api/routes"
 	"inshorts-news-api/services"
+	"inshorts-news-api/trending"
+	"inshorts-news-api/vectorindex"
 )
 
 func main() {
@@ -22,6 +39,10 @@ func main() {
 		log.Fatal("Database connection failed:", err)
 	}
 
+	// Background jobs stop and the server drains on SIGINT or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// Auto-migrate models
 	if err := db.AutoMigrate(); err != nil {
 		log.Fatal("Migration failed:", err)
@@ -29,17 +50,150 @@ func main() {
 
 	// Initialize dependencies
 	articleRepo := repositories.NewArticleRepository(db.GetDB())
-	llmService := services.NewLLMService(cfg.OpenAIKey)
-	articleService := services.NewArticleService(articleRepo, llmService)
-	articleHandler := handlers.NewArticleHandler(articleService, llmService)
+	llmProvider, err := services.NewLLMProvider(cfg)
+	if err != nil {
+		log.Fatal("LLM provider setup failed:", err)
+	}
+	llmService := services.NewLLMService(llmProvider, services.SummaryOptions{
+		Concurrency: cfg.LLMSummaryConcurrency,
+		Timeout:     cfg.LLMSummaryTimeout,
+		Budget:      cfg.LLMSummaryBudget,
+	})
+	summaryRepo := repositories.NewSummaryRepository(db.GetDB())
+	summaryService := services.NewSummaryService(summaryRepo, llmService)
+	embeddingRepo := repositories.NewEmbeddingRepository(db.GetDB())
+	embeddingService := services.NewEmbeddingService(embeddingRepo, llmService, vectorindex.New(vectorindex.Options{}))
+	trendingOptions := models.TrendingOptions{
+		Algorithm:       cfg.TrendingAlgorithm,
+		Weights:         cfg.TrendingWeights,
+		HalfLife:        cfg.TrendingHalfLife,
+		MinInteractions: cfg.TrendingMinInteractions,
+		UniqueUsers:     cfg.TrendingUniqueUsers,
+	}
+	if err := trendingOptions.WithDefaults().Validate(); err != nil {
+		log.Fatal("Invalid trending configuration:", err)
+	}
+	articleService := services.NewArticleService(articleRepo, summaryService, embeddingService, trendingOptions)
+	articleHandler := handlers.NewArticleHandler(articleService, llmService, gazetteer.Bundled())
+	eventOptions := models.EventOptions{
+		ExtraTypes:   cfg.EventExtraTypes,
+		MaxClockSkew: cfg.EventMaxClockSkew,
+		MaxAge:       cfg.EventMaxAge,
+	}
+	if err := eventOptions.Validate(); err != nil {
+		log.Fatal("Invalid event configuration:", err)
+	}
+	eventService := services.NewEventService(articleRepo, eventOptions)
+	fraudOptions := fraud.Options{
+		RateLimit:     cfg.FraudRateLimit,
+		RateWindow:    cfg.FraudRateWindow,
+		ViewSession:   cfg.FraudViewSession,
+		BotUserAgents: cfg.FraudBotUserAgents,
+	}
+	if err := fraudOptions.Validate(); err != nil {
+		log.Fatal("Invalid fraud configuration:", err)
+	}
+	eventService.SetFilter(fraud.NewFilter(fraudOptions))
+	eventHandler := handlers.NewEventHandler(eventService)
+
+	// Store events in batches off the request path
+	var bus *eventbus.Bus
+	if cfg.EventBufferSize > 0 {
+		busOptions := eventbus.Options{
+			Capacity:      cfg.EventBufferSize,
+			BatchSize:     cfg.EventFlushBatch,
+			FlushInterval: cfg.EventFlushInterval,
+			MaxRetries:    cfg.EventFlushRetries,
+			Overflow:      cfg.EventOverflow,
+		}
+		if err := busOptions.WithDefaults().Validate(); err != nil {
+			log.Fatal("Invalid event buffer configuration:", err)
+		}
+		bus = eventbus.New(articleRepo, busOptions)
+		eventService.SetPublisher(bus)
+		expvar.Publish("event_bus", expvar.Func(func() any { return bus.Stats() }))
+	}
+	clusterer := clustering.NewClusterer(articleRepo, clustering.Options{
+		Threshold: cfg.StorySimilarity,
+		Window:    cfg.StoryWindow,
+	})
+	adminService := services.NewArticleAdminService(articleRepo, clusterer)
+	adminService.SetEmbeddings(embeddingService)
+	feedRepo := repositories.NewFeedRepository(db.GetDB())
+	adminHandler := handlers.NewAdminHandler(adminService)
+	adminHandler.SetFeeds(feedRepo)
+
+	// Semantic search serves from memory, so load the stored embeddings
+	go func() {
+		loaded, err := embeddingService.LoadIndex(ctx)
+		if err != nil {
+			log.Printf("Loading the embedding index failed after %d vectors: %v", loaded, err)
+			return
+		}
+		log.Printf("Embedding index loaded with %d vectors", loaded)
+	}()
+
+	// Poll news feeds in the background
+	if cfg.IngestFeedsFile != "" {
+		feeds, err := ingestion.LoadFeeds(cfg.IngestFeedsFile)
+		if err != nil {
+			log.Fatal("Feed configuration failed:", err)
+		}
+		worker := ingestion.NewWorker(feeds, articleRepo, feedRepo, ingestion.Options{
+			Interval:  cfg.IngestInterval,
+			Timeout:   cfg.IngestTimeout,
+			Clusterer: clusterer,
+			Embedder:  embeddingService,
+		})
+		go worker.Run(ctx)
+		log.Printf("Ingesting %d feeds every %s", len(feeds), cfg.IngestInterval)
+	}
+
+	// Precompute trending scores in the background
+	if cfg.TrendingRefreshInterval > 0 {
+		materializer := trending.NewMaterializer(articleRepo, repositories.NewTrendingRepository(db.GetDB()), trending.Options{
+			Interval:   cfg.TrendingRefreshInterval,
+			StaleAfter: cfg.TrendingStaleAfter,
+			Windows:    cfg.TrendingWindows,
+			Trending:   trendingOptions,
+		})
+		articleService.SetTrendingCache(materializer)
+		go materializer.Run(ctx)
+		log.Printf("Refreshing trending scores every %s", cfg.TrendingRefreshInterval)
+	}
 
 	// Setup Gin router
 	r := gin.Default()
-	routes.SetupRoutes(r, articleHandler)
+	// The client IP rate limits events, so it is only read from headers set
+	// by known proxies
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES:", err)
+	}
+	if len(cfg.AdminTokens) == 0 {
+		log.Println("ADMIN_API_KEYS is empty, admin endpoints will reject every request")
+	}
+	routes.SetupRoutes(r, articleHandler, eventHandler, adminHandler, middleware.AdminAuth(cfg.AdminTokens))
 
 	// Start server
-	log.Printf("Server starting on port %s...", cfg.ServerPort)
-	if err := r.Run(":" + cfg.ServerPort); err != nil {
-		log.Fatal("Failed to start server:", err)
+	server := &http.Server{Addr: ":" + cfg.ServerPort, Handler: r}
+	go func() {
+		log.Printf("Server starting on port %s...", cfg.ServerPort)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("Failed to start server:", err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Println("Shutting down...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Server shutdown failed: %v", err)
+	}
+	if bus != nil {
+		if err := bus.Close(shutdownCtx); err != nil {
+			log.Printf("Draining buffered events failed: %v", err)
+		}
 	}
 }
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"inshorts-news-api/utils"
+)
+
+// AdminActorKey is the context key holding the authenticated admin's name
+const AdminActorKey = "admin_actor"
+
+// AdminAuth accepts requests carrying "Authorization: Bearer <token>" for one
+// of the configured tokens, which map to the actor named in the audit log
+func AdminAuth(tokens map[string]string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			utils.ErrorResponse(c, http.StatusUnauthorized, "Missing bearer token")
+			c.Abort()
+			return
+		}
+
+		for known, actor := range tokens {
+			if subtle.ConstantTimeCompare([]byte(token), []byte(known)) == 1 {
+				c.Set(AdminActorKey, actor)
+				c.Next()
+				return
+			}
+		}
+
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid bearer token")
+		c.Abort()
+	}
+}
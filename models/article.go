@@ -11,48 +11,122 @@ type Article struct {
 	ID              string         `gorm:"primaryKey" json:"id"`
 	Title           string         `gorm:"type:text;index:idx_title" json:"title"`
 	Description     string         `gorm:"type:text" json:"description"`
-	URL             string         `gorm:"type:text" json:"url"`
+	URL             string         `gorm:"type:text;index:idx_articles_url" json:"url"`
 	PublicationDate time.Time      `gorm:"index:idx_pub_date" json:"publication_date"`
 	SourceName      string         `gorm:"index:idx_source" json:"source_name"`
 	Category        pq.StringArray `gorm:"type:text[]" json:"category"` // Changed from []string
 	RelevanceScore  float64        `gorm:"index:idx_relevance" json:"relevance_score"`
-	Latitude        float64        `gorm:"index:idx_location" json:"latitude"`
-	Longitude       float64        `gorm:"index:idx_location" json:"longitude"`
+	Latitude        float64        `json:"latitude"`
+	Longitude       float64        `json:"longitude"`
+	StoryID         string         `gorm:"index:idx_story_id" json:"story_id,omitempty"`
 	CreatedAt       time.Time      `json:"-"`
 	UpdatedAt       time.Time      `json:"-"`
 	DeletedAt       gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// Populated only by full-text search and geo queries. Distance is in km
+	// and stays nil when no location was given.
+	TextRank float64  `gorm:"->;-:migration" json:"-"`
+	Snippet  string   `gorm:"->;-:migration" json:"-"`
+	Distance *float64 `gorm:"->;-:migration" json:"-"`
+
+	// Populated by semantic and hybrid search, never stored
+	Score float64 `gorm:"-" json:"-"`
+
+	// Populated by listings that collapse stories: how many other articles
+	// of the story matched
+	Duplicates int `gorm:"->;-:migration" json:"-"`
 }
 
+// CollapseStories keeps the first article of each story in an ordered list
+// and counts the others as its Duplicates, like the collapsing listings do
+// in SQL. An article without a story is keyed by its own ID, which is the
+// story ID its story would get.
+func CollapseStories(articles []Article) []Article {
+	kept := make([]Article, 0, len(articles))
+	position := make(map[string]int)
+
+	for _, article := range articles {
+		key := article.StoryID
+		if key == "" {
+			key = article.ID
+		}
+		if i, ok := position[key]; ok {
+			kept[i].Duplicates++
+			continue
+		}
+		article.Duplicates = 0
+		position[key] = len(kept)
+		kept = append(kept, article)
+	}
+
+	return kept
+}
+
+// ArticleResponse is an article as returned by the API. Optional fields are
+// only set by the listings that compute them: distance_km by geo queries,
+// trending_score and interaction_count by trending, text_rank by text search.
 type ArticleResponse struct {
-	Title           string         `json:"title"`
-	Description     string         `json:"description"`
-	URL             string         `json:"url"`
-	PublicationDate time.Time      `json:"publication_date"`
-	SourceName      string         `json:"source_name"`
-	Category        pq.StringArray `json:"category"` // Changed from []string
-	RelevanceScore  float64        `json:"relevance_score"`
-	LLMSummary      string         `json:"llm_summary"`
-	Latitude        float64        `json:"latitude"`
-	Longitude       float64        `json:"longitude"`
+	Title            string         `json:"title"`
+	Description      string         `json:"description"`
+	URL              string         `json:"url"`
+	PublicationDate  time.Time      `json:"publication_date"`
+	SourceName       string         `json:"source_name"`
+	Category         pq.StringArray `json:"category"` // Changed from []string
+	RelevanceScore   float64        `json:"relevance_score"`
+	LLMSummary       string         `json:"llm_summary"`
+	Latitude         float64        `json:"latitude"`
+	Longitude        float64        `json:"longitude"`
+	TextRank         float64        `json:"text_rank,omitempty"`
+	Snippet          string         `json:"snippet,omitempty"`
+	Score            float64        `json:"score,omitempty"`
+	DistanceKm       *float64       `json:"distance_km,omitempty"`
+	TrendingScore    *float64       `json:"trending_score,omitempty"`
+	InteractionCount *int64         `json:"interaction_count,omitempty"`
+	StoryID          string         `json:"story_id,omitempty"`
+	Duplicates       int            `json:"duplicates,omitempty"`
 }
 
+// QueryIntent is the analysed form of a free-text news query. Besides the
+// overall intent it carries typed slots that the query planner combines.
 type QueryIntent struct {
 	Intent   string   `json:"intent"`
 	Entities []string `json:"entities"`
 	Concepts []string `json:"concepts"`
+
+	Categories []string   `json:"categories,omitempty"`
+	Sources    []string   `json:"sources,omitempty"`
+	Location   string     `json:"location,omitempty"`
+	DateFrom   *time.Time `json:"date_from,omitempty"`
+	DateTo     *time.Time `json:"date_to,omitempty"`
+	MinScore   *float64   `json:"min_score,omitempty"`
+	Keywords   []string   `json:"keywords,omitempty"`
 }
 
+// UserEvent is an interaction with an article. UserID, SessionID and
+// DeviceID are optional identifiers chosen by the client. Clients that retry
+// may set IdempotencyKey, a globally unique value such as a UUID, so the
+// event is only stored once.
 type UserEvent struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	ArticleID string    `gorm:"index:idx_article" json:"article_id"`
-	EventType string    `gorm:"index:idx_event_type" json:"event_type"` // view, click, share
-	Latitude  float64   `json:"latitude"`
-	Longitude float64   `json:"longitude"`
-	Timestamp time.Time `gorm:"index:idx_timestamp" json:"timestamp"`
-	CreatedAt time.Time `json:"-"`
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	ArticleID      string    `gorm:"index:idx_article" json:"article_id"`
+	EventType      string    `gorm:"index:idx_event_type" json:"event_type"` // view, click, share
+	Latitude       float64   `json:"latitude"`
+	Longitude      float64   `json:"longitude"`
+	Timestamp      time.Time `gorm:"index:idx_timestamp" json:"timestamp"`
+	UserID         string    `gorm:"size:64;index:idx_user_events_user_id" json:"user_id,omitempty"`
+	SessionID      string    `gorm:"size:64;index:idx_user_events_session_id" json:"session_id,omitempty"`
+	DeviceID       string    `gorm:"size:64;index:idx_user_events_device_id" json:"device_id,omitempty"`
+	IdempotencyKey *string   `gorm:"size:128;uniqueIndex:idx_user_events_idempotency_key" json:"idempotency_key,omitempty"`
+	CreatedAt      time.Time `gorm:"index:idx_user_events_created_at" json:"-"`
+
+	// Suspicious events are kept for analysis but do not count towards
+	// trending; SuspiciousReason says which check flagged them
+	Suspicious       bool   `gorm:"not null;default:false" json:"suspicious,omitempty"`
+	SuspiciousReason string `gorm:"size:32" json:"suspicious_reason,omitempty"`
 }
 
 type TrendingArticle struct {
 	Article
-	TrendingScore float64 `json:"trending_score"`
+	TrendingScore    float64 `json:"trending_score"`
+	InteractionCount int64   `json:"interaction_count"`
 }
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ArticleInput is the body of admin create and update requests. Fields left
+// out of an update keep their current value.
+type ArticleInput struct {
+	Title           *string    `json:"title"`
+	Description     *string    `json:"description"`
+	URL             *string    `json:"url"`
+	PublicationDate *time.Time `json:"publication_date"`
+	SourceName      *string    `json:"source_name"`
+	Category        []string   `json:"category"`
+	RelevanceScore  *float64   `json:"relevance_score"`
+	Latitude        *float64   `json:"latitude"`
+	Longitude       *float64   `json:"longitude"`
+}
+
+// ApplyTo copies the fields present in the input onto article
+func (in ArticleInput) ApplyTo(article *Article) {
+	if in.Title != nil {
+		article.Title = *in.Title
+	}
+	if in.Description != nil {
+		article.Description = *in.Description
+	}
+	if in.URL != nil {
+		article.URL = *in.URL
+	}
+	if in.PublicationDate != nil {
+		article.PublicationDate = *in.PublicationDate
+	}
+	if in.SourceName != nil {
+		article.SourceName = *in.SourceName
+	}
+	if in.Category != nil {
+		article.Category = pq.StringArray(in.Category)
+	}
+	if in.RelevanceScore != nil {
+		article.RelevanceScore = *in.RelevanceScore
+	}
+	if in.Latitude != nil {
+		article.Latitude = *in.Latitude
+	}
+	if in.Longitude != nil {
+		article.Longitude = *in.Longitude
+	}
+}
+
+// AdminArticle is an article as shown to admins, including bookkeeping
+// fields hidden from the public API
+type AdminArticle struct {
+	Article
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at"`
+}
+
+func NewAdminArticle(article Article) AdminArticle {
+	admin := AdminArticle{
+		Article:   article,
+		CreatedAt: article.CreatedAt,
+		UpdatedAt: article.UpdatedAt,
+	}
+	if article.DeletedAt.Valid {
+		deletedAt := article.DeletedAt.Time
+		admin.DeletedAt = &deletedAt
+	}
+	return admin
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+const (
+	AuditActionCreate  = "create"
+	AuditActionUpdate  = "update"
+	AuditActionDelete  = "delete"
+	AuditActionRestore = "restore"
+)
+
+// ArticleAuditLog records an admin change to an article. Changes holds the
+// created article for creates and {field: {old, new}} for updates.
+type ArticleAuditLog struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	ArticleID string    `gorm:"index:idx_audit_article" json:"article_id"`
+	Actor     string    `gorm:"index:idx_audit_actor" json:"actor"`
+	Action    string    `json:"action"`
+	Changes   string    `gorm:"type:jsonb;default:'{}'" json:"changes"`
+	CreatedAt time.Time `gorm:"index:idx_audit_created_at" json:"created_at"`
+}
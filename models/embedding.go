@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ArticleEmbedding stores the embedding of an article's title and
+// description. Like ArticleSummary it is keyed by article and model, and
+// ContentHash ties it to the text it was computed from.
+type ArticleEmbedding struct {
+	ID          uint            `gorm:"primaryKey" json:"id"`
+	ArticleID   string          `gorm:"uniqueIndex:idx_embedding_article_model" json:"article_id"`
+	Model       string          `gorm:"uniqueIndex:idx_embedding_article_model" json:"model"`
+	ContentHash string          `gorm:"size:64" json:"content_hash"`
+	Vector      pq.Float32Array `gorm:"type:real[]" json:"-"`
+	CreatedAt   time.Time       `json:"-"`
+	UpdatedAt   time.Time       `json:"-"`
+}
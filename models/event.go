@@ -0,0 +1,104 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// Event types every deployment accepts
+const (
+	EventView  = "view"
+	EventClick = "click"
+	EventShare = "share"
+)
+
+// eventTypePattern allows names such as "bookmark" and "dwell_30s"
+var eventTypePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,31}$`)
+
+// Outcomes of an event in a batch. Discarded events were valid but dropped
+// by the abuse filter, such as events from bots.
+const (
+	EventAccepted  = "accepted"
+	EventDuplicate = "duplicate"
+	EventRejected  = "rejected"
+	EventDiscarded = "discarded"
+)
+
+// EventResult is the outcome of the event at Index in a batch. Error says
+// why a rejected or discarded event was not stored.
+type EventResult struct {
+	Index  int    `json:"index"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// EventClient is who sent an event, as seen by the server
+type EventClient struct {
+	IP        string
+	UserAgent string
+}
+
+// EventOptions sets which events are accepted
+type EventOptions struct {
+	// ExtraTypes are accepted besides view, click and share, such as
+	// bookmark or dwell. They weigh nothing in trending unless given a
+	// trending weight.
+	ExtraTypes []string
+	// MaxClockSkew is how far ahead of the server a client timestamp may
+	// be; timestamps within it are clamped to the server time
+	MaxClockSkew time.Duration
+	// MaxAge is how old a client timestamp may be, bounding how long
+	// clients can buffer events offline
+	MaxAge time.Duration
+}
+
+// WithDefaults fills in the settings that were left empty
+func (o EventOptions) WithDefaults() EventOptions {
+	if o.MaxClockSkew <= 0 {
+		o.MaxClockSkew = 5 * time.Minute
+	}
+	if o.MaxAge <= 0 {
+		o.MaxAge = 72 * time.Hour
+	}
+	return o
+}
+
+func (o EventOptions) Validate() error {
+	for _, name := range o.ExtraTypes {
+		if !eventTypePattern.MatchString(name) {
+			return fmt.Errorf("invalid event type %q", name)
+		}
+	}
+	if o.MaxClockSkew < 0 || o.MaxAge < 0 {
+		return errors.New("event time bounds must not be negative")
+	}
+	return nil
+}
+
+// EventTypes is the registry of accepted event types
+type EventTypes map[string]bool
+
+func NewEventTypes(extra ...string) EventTypes {
+	types := EventTypes{EventView: true, EventClick: true, EventShare: true}
+	for _, name := range extra {
+		types[name] = true
+	}
+	return types
+}
+
+func (t EventTypes) Has(name string) bool {
+	return t[name]
+}
+
+// Names lists the types in alphabetical order
+func (t EventTypes) Names() []string {
+	names := make([]string, 0, len(t))
+	for name := range t {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
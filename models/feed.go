@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// FeedStatus records the outcome of the latest fetch of an RSS or Atom feed
+// and the validators used to make the next fetch conditional
+type FeedStatus struct {
+	FeedURL             string     `gorm:"primaryKey;type:text" json:"feed_url"`
+	Name                string     `json:"name"`
+	ETag                string     `gorm:"type:text" json:"etag,omitempty"`
+	LastModified        string     `gorm:"type:text" json:"last_modified,omitempty"`
+	LastFetchedAt       *time.Time `json:"last_fetched_at"`
+	LastSuccessAt       *time.Time `json:"last_success_at"`
+	LastStatusCode      int        `json:"last_status_code"`
+	LastError           string     `gorm:"type:text" json:"last_error,omitempty"`
+	ItemsSeen           int        `json:"items_seen"`
+	ItemsInserted       int        `json:"items_inserted"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	SortByDate      = "date"
+	SortByRelevance = "relevance"
+	SortByDistance  = "distance"
+	SortByTextRank  = "text_rank"
+)
+
+// Search modes: keyword uses full-text search only, semantic ranks by
+// embedding similarity and hybrid blends both with relevance_score
+const (
+	SearchKeyword  = "keyword"
+	SearchSemantic = "semantic"
+	SearchHybrid   = "hybrid"
+)
+
+// GeoFilter restricts articles to a radius around a point. Place names the
+// point when it was resolved from a place name.
+type GeoFilter struct {
+	Lat      float64 `json:"lat"`
+	Lon      float64 `json:"lon"`
+	RadiusKm float64 `json:"radius_km"`
+	Place    string  `json:"place,omitempty"`
+}
+
+// ArticleFilter combines any number of article filters into one query.
+// Zero values leave the corresponding filter off.
+type ArticleFilter struct {
+	Categories []string // matches articles in any of them, case-insensitively
+	Sources    []string // matches any source by substring, case-insensitively
+	MinScore   *float64
+	MaxScore   *float64
+	From       *time.Time
+	To         *time.Time
+	Text       string // websearch syntax, as for SearchByText
+	Near       *GeoFilter
+	Within     *GeoArea
+	Sort       string
+}
+
+// Validate checks that the sort order can be computed for the filter
+func (f ArticleFilter) Validate() error {
+	switch f.Sort {
+	case SortByDate, SortByRelevance:
+	case SortByDistance:
+		if f.Near == nil {
+			return errors.New("sorting by distance requires a location")
+		}
+	case SortByTextRank:
+		if f.Text == "" {
+			return errors.New("sorting by text rank requires a text query")
+		}
+	default:
+		return errors.New("unknown sort: " + f.Sort)
+	}
+
+	if f.MinScore != nil && f.MaxScore != nil && *f.MinScore > *f.MaxScore {
+		return errors.New("min_score is greater than max_score")
+	}
+
+	if f.Near != nil && f.Near.RadiusKm <= 0 {
+		return errors.New("radius must be positive")
+	}
+
+	if f.Within != nil {
+		if err := f.Within.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if f.From != nil && f.To != nil && f.From.After(*f.To) {
+		return errors.New("date range starts after it ends")
+	}
+
+	return nil
+}
@@ -0,0 +1,205 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// MaxZoom is the deepest map zoom level markers can be clustered for
+const MaxZoom = 22
+
+// maxPolygonVertices bounds the size of a polygon accepted in a request
+const maxPolygonVertices = 10000
+
+// BoundingBox is a lat/lon rectangle such as a map viewport. Boxes that
+// cross the antimeridian are not supported.
+type BoundingBox struct {
+	MinLat float64 `json:"min_lat"`
+	MinLon float64 `json:"min_lon"`
+	MaxLat float64 `json:"max_lat"`
+	MaxLon float64 `json:"max_lon"`
+}
+
+func (b BoundingBox) Contains(lat, lon float64) bool {
+	return lat >= b.MinLat && lat <= b.MaxLat && lon >= b.MinLon && lon <= b.MaxLon
+}
+
+func (b BoundingBox) Validate() error {
+	if b.MinLat < -90 || b.MaxLat > 90 || b.MinLon < -180 || b.MaxLon > 180 {
+		return errors.New("bounding box is outside valid coordinates")
+	}
+	if b.MinLat > b.MaxLat || b.MinLon > b.MaxLon {
+		return errors.New("bounding box minimum is greater than its maximum")
+	}
+	return nil
+}
+
+// GeoPolygon is a GeoJSON Polygon or MultiPolygon, kept as a list of
+// polygons whose first ring is the outline and the others holes. Points are
+// [lon, lat] and edges are straight lines in lon/lat, as in GeoJSON.
+type GeoPolygon struct {
+	Polygons [][][][2]float64
+}
+
+// ParseGeoJSONPolygon reads a Polygon or MultiPolygon geometry, or a
+// Feature holding one
+func ParseGeoJSONPolygon(data []byte) (*GeoPolygon, error) {
+	var geometry struct {
+		Type        string          `json:"type"`
+		Coordinates json.RawMessage `json:"coordinates"`
+		Geometry    json.RawMessage `json:"geometry"`
+	}
+	if err := json.Unmarshal(data, &geometry); err != nil {
+		return nil, errors.New("polygon is not valid GeoJSON")
+	}
+
+	var polygon GeoPolygon
+	switch geometry.Type {
+	case "Feature":
+		if len(geometry.Geometry) == 0 {
+			return nil, errors.New("feature has no geometry")
+		}
+		return ParseGeoJSONPolygon(geometry.Geometry)
+	case "Polygon":
+		var rings [][][2]float64
+		if err := json.Unmarshal(geometry.Coordinates, &rings); err != nil {
+			return nil, errors.New("polygon coordinates are not valid")
+		}
+		polygon.Polygons = [][][][2]float64{rings}
+	case "MultiPolygon":
+		if err := json.Unmarshal(geometry.Coordinates, &polygon.Polygons); err != nil {
+			return nil, errors.New("multipolygon coordinates are not valid")
+		}
+	default:
+		return nil, fmt.Errorf("expected a Polygon or MultiPolygon, got %q", geometry.Type)
+	}
+
+	return &polygon, polygon.Validate()
+}
+
+func (p GeoPolygon) Validate() error {
+	if len(p.Polygons) == 0 {
+		return errors.New("polygon is empty")
+	}
+
+	vertices := 0
+	for _, rings := range p.Polygons {
+		if len(rings) == 0 {
+			return errors.New("polygon has no outline")
+		}
+		for _, ring := range rings {
+			if len(ring) < 4 {
+				return errors.New("polygon rings need at least 4 points")
+			}
+			if ring[0] != ring[len(ring)-1] {
+				return errors.New("polygon rings must end where they start")
+			}
+			for _, point := range ring {
+				if point[0] < -180 || point[0] > 180 || point[1] < -90 || point[1] > 90 {
+					return errors.New("polygon is outside valid coordinates")
+				}
+			}
+			vertices += len(ring)
+		}
+	}
+
+	if vertices > maxPolygonVertices {
+		return fmt.Errorf("polygon has more than %d points", maxPolygonVertices)
+	}
+	return nil
+}
+
+// GeoJSON returns the polygon as a MultiPolygon geometry
+func (p GeoPolygon) GeoJSON() string {
+	data, _ := json.Marshal(map[string]interface{}{
+		"type":        "MultiPolygon",
+		"coordinates": p.Polygons,
+	})
+	return string(data)
+}
+
+// Contains reports whether a point is inside any polygon and outside its
+// holes
+func (p GeoPolygon) Contains(lat, lon float64) bool {
+	for _, rings := range p.Polygons {
+		if !ringContains(rings[0], lat, lon) {
+			continue
+		}
+		inHole := false
+		for _, hole := range rings[1:] {
+			if ringContains(hole, lat, lon) {
+				inHole = true
+				break
+			}
+		}
+		if !inHole {
+			return true
+		}
+	}
+	return false
+}
+
+// ringContains casts a ray east from the point and counts the edges it
+// crosses
+func ringContains(ring [][2]float64, lat, lon float64) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		xi, yi := ring[i][0], ring[i][1]
+		xj, yj := ring[j][0], ring[j][1]
+		if (yi > lat) != (yj > lat) && lon < (xj-xi)*(lat-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// GeoArea restricts articles to a bounding box or a polygon
+type GeoArea struct {
+	Box     *BoundingBox
+	Polygon *GeoPolygon
+}
+
+func (a GeoArea) Contains(lat, lon float64) bool {
+	if a.Box != nil && !a.Box.Contains(lat, lon) {
+		return false
+	}
+	if a.Polygon != nil && !a.Polygon.Contains(lat, lon) {
+		return false
+	}
+	return true
+}
+
+func (a GeoArea) Validate() error {
+	if (a.Box == nil) == (a.Polygon == nil) {
+		return errors.New("expected either a bounding box or a polygon")
+	}
+	if a.Box != nil {
+		return a.Box.Validate()
+	}
+	return a.Polygon.Validate()
+}
+
+// GridCellSize is the width in degrees of the marker clustering cells at a
+// zoom level: four cells to a 256px map tile
+func GridCellSize(zoom int) float64 {
+	return 360 / (4 * math.Pow(2, float64(zoom)))
+}
+
+// MarkerCluster groups the articles in one grid cell. Latitude and
+// Longitude are the mean position of its articles, for placing the marker.
+type MarkerCluster struct {
+	Latitude  float64     `json:"latitude"`
+	Longitude float64     `json:"longitude"`
+	Count     int64       `json:"count"`
+	Bounds    BoundingBox `json:"bounds"`
+}
+
+// WithinPage is a page of articles inside an area, with all matching
+// articles clustered into markers when a zoom level was given
+type WithinPage struct {
+	Articles   []ArticleResponse `json:"articles"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+	Clusters   []MarkerCluster   `json:"clusters,omitempty"`
+}
@@ -0,0 +1,38 @@
+package models
+
+// GeoJSONPoint is a GeoJSON Point; coordinates are [lon, lat]
+type GeoJSONPoint struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+type GeoJSONFeature struct {
+	Type       string          `json:"type"`
+	Geometry   GeoJSONPoint    `json:"geometry"`
+	Properties ArticleResponse `json:"properties"`
+}
+
+// FeatureCollection renders articles as GeoJSON for map layers. Paging and
+// clusters ride along as foreign members, which GeoJSON readers ignore.
+type FeatureCollection struct {
+	Type       string           `json:"type"`
+	Features   []GeoJSONFeature `json:"features"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+	Clusters   []MarkerCluster  `json:"clusters,omitempty"`
+}
+
+func NewFeatureCollection(articles []ArticleResponse) FeatureCollection {
+	features := make([]GeoJSONFeature, len(articles))
+	for i, article := range articles {
+		features[i] = GeoJSONFeature{
+			Type: "Feature",
+			Geometry: GeoJSONPoint{
+				Type:        "Point",
+				Coordinates: [2]float64{article.Longitude, article.Latitude},
+			},
+			Properties: article,
+		}
+	}
+
+	return FeatureCollection{Type: "FeatureCollection", Features: features}
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Cursor marks the last article of a page so the next page can resume
+// right after it. Time or Value holds the sort column, ID breaks ties.
+type Cursor struct {
+	Time  time.Time `json:"t,omitempty"`
+	Value float64   `json:"v,omitempty"`
+	ID    string    `json:"id"`
+}
+
+type PageRequest struct {
+	Cursor   *Cursor
+	PageSize int
+	// Collapse keeps only the first article of each story, across pages
+	Collapse bool
+}
+
+type ArticlePage struct {
+	Articles   []ArticleResponse `json:"articles"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// ArticleSummary caches an LLM summary. ContentHash covers the title and
+// description the summary was generated from, so edits invalidate it.
+type ArticleSummary struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	ArticleID   string    `gorm:"uniqueIndex:idx_summary_article_model" json:"article_id"`
+	Model       string    `gorm:"uniqueIndex:idx_summary_article_model" json:"model"`
+	ContentHash string    `gorm:"size:64" json:"content_hash"`
+	Summary     string    `gorm:"type:text" json:"summary"`
+	CreatedAt   time.Time `json:"-"`
+	UpdatedAt   time.Time `json:"-"`
+}
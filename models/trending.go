@@ -0,0 +1,112 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// Trending algorithms:
+//   - classic weighs the window's events and divides by the hours since the
+//     latest one
+//   - decay halves each event's weight every HalfLife, so old interactions
+//     fade out smoothly
+//   - velocity is the growth of weighted events over the window before, so
+//     stories gaining attention rise above ones that are merely popular
+const (
+	TrendingClassic  = "classic"
+	TrendingDecay    = "decay"
+	TrendingVelocity = "velocity"
+)
+
+// DefaultEventWeights are the weights of the original trending formula
+var DefaultEventWeights = map[string]float64{
+	EventShare: 3.0,
+	EventClick: 2.0,
+	EventView:  1.0,
+}
+
+// TrendingOptions tunes how articles are ranked by their interactions
+type TrendingOptions struct {
+	Algorithm string
+	// Weights per event type; unknown types weigh nothing
+	Weights  map[string]float64
+	HalfLife time.Duration
+	// MinInteractions is how many events in the window an article needs
+	// before it gets a trending score at all
+	MinInteractions int
+	// UniqueUsers counts each user's events of a type once per window and
+	// reports users rather than events as interactions. Events without a
+	// user or device ID each count as their own user.
+	UniqueUsers bool
+}
+
+// WithDefaults fills in the settings that were left empty
+func (o TrendingOptions) WithDefaults() TrendingOptions {
+	if o.Algorithm == "" {
+		o.Algorithm = TrendingClassic
+	}
+	if len(o.Weights) == 0 {
+		o.Weights = DefaultEventWeights
+	}
+	if o.HalfLife <= 0 {
+		o.HalfLife = 6 * time.Hour
+	}
+	return o
+}
+
+func (o TrendingOptions) Validate() error {
+	switch o.Algorithm {
+	case TrendingClassic, TrendingDecay, TrendingVelocity:
+	default:
+		return errors.New("unknown trending algorithm: " + o.Algorithm)
+	}
+	if o.MinInteractions < 0 {
+		return errors.New("min_interactions cannot be negative")
+	}
+	return nil
+}
+
+// TrendingQuery asks for the articles trending around a point over the
+// last Window
+type TrendingQuery struct {
+	TrendingOptions
+	Lat      float64
+	Lon      float64
+	RadiusKm float64
+	Limit    int
+	Window   time.Duration
+}
+
+// TrendingPage names the algorithm that ranked the articles and whether it
+// counted unique users, so clients in an experiment can tell the variants
+// apart. Materialized is set when the scores were precomputed rather than
+// aggregated for this request.
+type TrendingPage struct {
+	Algorithm    string            `json:"algorithm"`
+	UniqueUsers  bool              `json:"unique_users"`
+	Materialized bool              `json:"materialized"`
+	Articles     []ArticleResponse `json:"articles"`
+}
+
+// TrendingScore is the materialized trending score of an article for one
+// window length and algorithm. Cell is the grid cell of the article's
+// location, which is the unit scores are refreshed in.
+type TrendingScore struct {
+	ArticleID        string    `gorm:"primaryKey" json:"article_id"`
+	WindowHours      int       `gorm:"primaryKey" json:"window_hours"`
+	Algorithm        string    `gorm:"primaryKey;size:20" json:"algorithm"`
+	Cell             string    `gorm:"size:32;index:idx_trending_scores_cell" json:"cell"`
+	Score            float64   `json:"score"`
+	InteractionCount int64     `json:"interaction_count"`
+	RefreshedAt      time.Time `json:"refreshed_at"`
+}
+
+// TrendingCell records when the scores of a grid cell were last refreshed.
+// Empty cells had no events to score.
+type TrendingCell struct {
+	Cell        string    `gorm:"primaryKey;size:32" json:"cell"`
+	WindowHours int       `gorm:"primaryKey" json:"window_hours"`
+	Algorithm   string    `gorm:"primaryKey;size:20" json:"algorithm"`
+	RefreshedAt time.Time `gorm:"index:idx_trending_cells_refreshed_at" json:"refreshed_at"`
+	Empty       bool      `gorm:"not null;default:false" json:"empty"`
+}
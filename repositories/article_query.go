@@ -0,0 +1,135 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"inshorts-news-api/models"
+)
+
+// FindArticles runs one query combining every filter that is set on filter,
+// ordered by filter.Sort and paginated by keyset like the single-filter
+// listings.
+func (r *ArticleRepository) FindArticles(ctx context.Context, filter models.ArticleFilter, page models.PageRequest) ([]models.Article, *models.Cursor, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	query, args := buildFilterQuery(filter)
+	return r.listPage(ctx, query, args, filter.Sort, page)
+}
+
+// buildFilterQuery computes text rank and distance in a query that
+// listPage wraps, so the page can be filtered, ordered and paginated on
+// them by name
+func buildFilterQuery(filter models.ArticleFilter) (string, []interface{}) {
+	var selectArgs, fromArgs, whereArgs []interface{}
+
+	selects := []string{"articles.*"}
+	from := "articles"
+	where := []string{"deleted_at IS NULL"}
+
+	if filter.Text != "" {
+		selects = append(selects,
+			"ts_rank("+searchVector+", q)::float8 AS text_rank",
+			"ts_headline('english', description, q, ?) AS snippet")
+		selectArgs = append(selectArgs, snippetOptions)
+
+		from += ", websearch_to_tsquery('english', ?) AS q"
+		fromArgs = append(fromArgs, filter.Text)
+
+		where = append(where, searchVector+" @@ q")
+	}
+
+	if filter.Near != nil {
+		selects = append(selects, distanceSQL+" AS distance")
+		selectArgs = append(selectArgs, filter.Near.Lon, filter.Near.Lat)
+
+		where = append(where, withinSQL)
+		whereArgs = append(whereArgs, filter.Near.Lon, filter.Near.Lat, filter.Near.RadiusKm*1000)
+	}
+
+	if filter.Within != nil {
+		condition, args := withinAreaSQL(*filter.Within)
+		where = append(where, condition)
+		whereArgs = append(whereArgs, args...)
+	}
+
+	if len(filter.Categories) > 0 {
+		lowered := make([]string, len(filter.Categories))
+		for i, c := range filter.Categories {
+			lowered[i] = strings.ToLower(c)
+		}
+		where = append(where, "EXISTS (SELECT 1 FROM unnest(category) AS c WHERE LOWER(c) = ANY(?))")
+		whereArgs = append(whereArgs, pq.StringArray(lowered))
+	}
+
+	if len(filter.Sources) > 0 {
+		conditions := make([]string, len(filter.Sources))
+		for i, source := range filter.Sources {
+			conditions[i] = "LOWER(source_name) LIKE LOWER(?)"
+			whereArgs = append(whereArgs, "%"+source+"%")
+		}
+		where = append(where, "("+strings.Join(conditions, " OR ")+")")
+	}
+
+	if filter.MinScore != nil {
+		where = append(where, "relevance_score >= ?")
+		whereArgs = append(whereArgs, *filter.MinScore)
+	}
+
+	if filter.MaxScore != nil {
+		where = append(where, "relevance_score <= ?")
+		whereArgs = append(whereArgs, *filter.MaxScore)
+	}
+
+	if filter.From != nil {
+		where = append(where, "publication_date >= ?")
+		whereArgs = append(whereArgs, *filter.From)
+	}
+
+	if filter.To != nil {
+		where = append(where, "publication_date <= ?")
+		whereArgs = append(whereArgs, *filter.To)
+	}
+
+	query := fmt.Sprintf(`
+            SELECT %s
+            FROM %s
+            WHERE %s`,
+		strings.Join(selects, ", "), from, strings.Join(where, " AND "))
+
+	args := append(selectArgs, fromArgs...)
+	args = append(args, whereArgs...)
+	return query, args
+}
+
+// sortClauses returns the keyset condition and ORDER BY for a sort
+func sortClauses(sort string) (string, string) {
+	switch sort {
+	case models.SortByRelevance:
+		return "(relevance_score, id) < (?, ?)", "relevance_score DESC, id DESC"
+	case models.SortByDistance:
+		return "(distance, id) > (?, ?)", "distance, id"
+	case models.SortByTextRank:
+		return "(text_rank, id) < (?, ?)", "text_rank DESC, id DESC"
+	default:
+		return "(publication_date, id) < (?, ?)", "publication_date DESC, id DESC"
+	}
+}
+
+func sortKey(sort string) func(models.Article) models.Cursor {
+	switch sort {
+	case models.SortByRelevance:
+		return byRelevanceScore
+	case models.SortByDistance:
+		return byDistance
+	case models.SortByTextRank:
+		return byTextRank
+	default:
+		return byPublicationDate
+	}
+}
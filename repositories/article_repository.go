@@ -1,15 +1,19 @@
 package repositories
 
 import (
-	"math"
+	"context"
+	"errors"
 	"strings"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"inshorts-news-api/models"
 )
 
+var ErrArticleNotFound = errors.New("article not found")
+
 type ArticleRepository struct {
 	db *gorm.DB
 }
@@ -18,190 +22,443 @@ func NewArticleRepository(db *gorm.DB) *ArticleRepository {
 	return &ArticleRepository{db: db}
 }
 
-func (r *ArticleRepository) Create(article *models.Article) error {
-	return r.db.Create(article).Error
+func (r *ArticleRepository) Create(ctx context.Context, article *models.Article) error {
+	return r.db.WithContext(ctx).Create(article).Error
+}
+
+func (r *ArticleRepository) BulkCreate(ctx context.Context, articles []models.Article) error {
+	return r.db.WithContext(ctx).CreateInBatches(articles, 100).Error
 }
 
-func (r *ArticleRepository) BulkCreate(articles []models.Article) error {
-	return r.db.CreateInBatches(articles, 100).Error
+// CreateNew inserts the articles whose URL is not stored yet, counting soft
+// deleted articles as stored, and returns the ones it inserted
+func (r *ArticleRepository) CreateNew(ctx context.Context, articles []models.Article) ([]models.Article, error) {
+	if len(articles) == 0 {
+		return nil, nil
+	}
+
+	urls := make([]string, len(articles))
+	for i, article := range articles {
+		urls[i] = article.URL
+	}
+
+	existing, err := r.StoredURLs(ctx, urls)
+	if err != nil {
+		return nil, err
+	}
+
+	fresh := newByURL(articles, existing)
+	if len(fresh) == 0 {
+		return nil, nil
+	}
+
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(fresh, 100)
+	if result.Error != nil || int(result.RowsAffected) == len(fresh) {
+		return fresh, result.Error
+	}
+
+	// A concurrent insert won some of the conflicts. IDs are unique to each
+	// attempt, so the rows stored with our IDs are the ones we inserted.
+	ids := make([]string, len(fresh))
+	for i, article := range fresh {
+		ids[i] = article.ID
+	}
+	var storedIDs []string
+	if err := r.db.WithContext(ctx).Unscoped().Model(&models.Article{}).Where("id IN ?", ids).Pluck("id", &storedIDs).Error; err != nil {
+		return nil, err
+	}
+	stored := make(map[string]bool, len(storedIDs))
+	for _, id := range storedIDs {
+		stored[id] = true
+	}
+	inserted := fresh[:0]
+	for _, article := range fresh {
+		if stored[article.ID] {
+			inserted = append(inserted, article)
+		}
+	}
+	return inserted, nil
+}
+
+// StoredURLs returns those of urls that stored articles have, including
+// soft-deleted ones
+func (r *ArticleRepository) StoredURLs(ctx context.Context, urls []string) ([]string, error) {
+	var existing []string
+	if len(urls) == 0 {
+		return existing, nil
+	}
+	err := r.db.WithContext(ctx).Unscoped().Model(&models.Article{}).
+		Where("url IN ?", urls).
+		Pluck("url", &existing).Error
+	return existing, err
+}
+
+// GetByID finds an article, including soft-deleted ones when asked to
+func (r *ArticleRepository) GetByID(ctx context.Context, id string, includeDeleted bool) (*models.Article, error) {
+	query := r.db.WithContext(ctx)
+	if includeDeleted {
+		query = query.Unscoped()
+	}
+
+	var article models.Article
+	err := query.Where("id = ?", id).First(&article).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrArticleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &article, nil
 }
 
-func (r *ArticleRepository) GetByCategory(category string, limit int) ([]models.Article, error) {
+// GetByIDs returns the live articles among ids, in no particular order
+func (r *ArticleRepository) GetByIDs(ctx context.Context, ids []string) ([]models.Article, error) {
 	var articles []models.Article
-	err := r.db.Where("? = ANY(category)", category).
-		Order("publication_date DESC").
-		Limit(limit).
-		Find(&articles).Error
+	if len(ids) == 0 {
+		return articles, nil
+	}
+
+	err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&articles).Error
 	return articles, err
 }
 
-func (r *ArticleRepository) GetBySource(source string, limit int) ([]models.Article, error) {
+func (r *ArticleRepository) Update(ctx context.Context, article *models.Article) error {
+	result := r.db.WithContext(ctx).Model(article).Select("*").Omit("created_at", "deleted_at").Updates(article)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrArticleNotFound
+	}
+	return nil
+}
+
+// Delete soft-deletes an article
+func (r *ArticleRepository) Delete(ctx context.Context, id string) error {
+	result := r.db.WithContext(ctx).Where("id = ?", id).Delete(&models.Article{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrArticleNotFound
+	}
+	return nil
+}
+
+// Restore undoes a soft delete
+func (r *ArticleRepository) Restore(ctx context.Context, id string) error {
+	result := r.db.WithContext(ctx).Unscoped().Model(&models.Article{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrArticleNotFound
+	}
+	return nil
+}
+
+func (r *ArticleRepository) CreateAuditLog(ctx context.Context, entry *models.ArticleAuditLog) error {
+	return r.db.WithContext(ctx).Create(entry).Error
+}
+
+// Transaction runs fn against a store bound to one database transaction
+func (r *ArticleRepository) Transaction(ctx context.Context, fn func(store ArticleStore) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&ArticleRepository{db: tx})
+	})
+}
+
+// ListAfterID walks all articles in ID order, for batch jobs
+func (r *ArticleRepository) ListAfterID(ctx context.Context, afterID string, limit int) ([]models.Article, error) {
 	var articles []models.Article
-	err := r.db.Where("LOWER(source_name) LIKE LOWER(?)", "%"+source+"%").
-		Order("publication_date DESC").
+	err := r.db.WithContext(ctx).Where("id > ?", afterID).
+		Order("id").
 		Limit(limit).
 		Find(&articles).Error
 	return articles, err
 }
 
-func (r *ArticleRepository) GetByScore(minScore float64, limit int) ([]models.Article, error) {
+// ListPublishedBetween returns the articles published in [from, to], which
+// story clustering compares new articles against
+func (r *ArticleRepository) ListPublishedBetween(ctx context.Context, from, to time.Time) ([]models.Article, error) {
 	var articles []models.Article
-	err := r.db.Where("relevance_score >= ?", minScore).
-		Order("relevance_score DESC").
-		Limit(limit).
+	err := r.db.WithContext(ctx).
+		Select("id", "title", "description", "publication_date", "story_id").
+		Where("publication_date BETWEEN ? AND ?", from, to).
 		Find(&articles).Error
 	return articles, err
 }
 
-func (r *ArticleRepository) SearchByText(query string, limit int) ([]models.Article, error) {
-	var articles []models.Article
-	searchQuery := "%" + strings.ToLower(query) + "%"
+// SetStoryIDs moves articles, keyed by ID, to the given stories
+func (r *ArticleRepository) SetStoryIDs(ctx context.Context, storyIDs map[string]string) error {
+	if len(storyIDs) == 0 {
+		return nil
+	}
 
-	err := r.db.Where("LOWER(title) LIKE ? OR LOWER(description) LIKE ?", searchQuery, searchQuery).
-		Order("relevance_score DESC, publication_date DESC").
-		Limit(limit).
-		Find(&articles).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for id, storyID := range storyIDs {
+			err := tx.Model(&models.Article{}).Where("id = ?", id).
+				UpdateColumn("story_id", storyID).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
 
+// GetByStory returns the articles of a story, oldest first
+func (r *ArticleRepository) GetByStory(ctx context.Context, storyID string) ([]models.Article, error) {
+	var articles []models.Article
+	err := r.db.WithContext(ctx).Where("story_id = ?", storyID).
+		Order("publication_date, id").
+		Find(&articles).Error
 	return articles, err
 }
 
-func (r *ArticleRepository) GetNearby(lat, lon, radiusKm float64, limit int) ([]models.Article, error) {
-	var articles []models.Article
+func (r *ArticleRepository) GetByCategory(ctx context.Context, category string, page models.PageRequest) ([]models.Article, *models.Cursor, error) {
+	return r.listPage(ctx, `SELECT * FROM articles WHERE deleted_at IS NULL AND ? = ANY(category)`,
+		[]interface{}{category}, models.SortByDate, page)
+}
 
-	// Using subquery to properly handle the distance calculation
-	query := `
-        SELECT * FROM (
-            SELECT *, (
-                6371 * acos(
-                    cos(radians(?)) * cos(radians(latitude)) * 
-                    cos(radians(longitude) - radians(?)) + 
-                    sin(radians(?)) * sin(radians(latitude))
-                )
-            ) AS distance
-            FROM articles
-            WHERE deleted_at IS NULL
-        ) AS articles_with_distance
-        WHERE distance < ?
-        ORDER BY distance
-        LIMIT ?
-    `
+func (r *ArticleRepository) GetBySource(ctx context.Context, source string, page models.PageRequest) ([]models.Article, *models.Cursor, error) {
+	return r.listPage(ctx, `SELECT * FROM articles WHERE deleted_at IS NULL AND LOWER(source_name) LIKE LOWER(?)`,
+		[]interface{}{"%" + source + "%"}, models.SortByDate, page)
+}
 
-	err := r.db.Raw(query, lat, lon, lat, radiusKm, limit).Scan(&articles).Error
-	return articles, err
+func (r *ArticleRepository) GetByScore(ctx context.Context, minScore float64, page models.PageRequest) ([]models.Article, *models.Cursor, error) {
+	return r.listPage(ctx, `SELECT * FROM articles WHERE deleted_at IS NULL AND relevance_score >= ?`,
+		[]interface{}{minScore}, models.SortByRelevance, page)
 }
 
-func (r *ArticleRepository) CreateUserEvent(event *models.UserEvent) error {
-	return r.db.Create(event).Error
+// listPage runs one page of inner, a query of articles, in the order of
+// sort. See pageSQL.
+func (r *ArticleRepository) listPage(ctx context.Context, inner string, args []interface{}, sort string, page models.PageRequest) ([]models.Article, *models.Cursor, error) {
+	keyset, order := sortClauses(sort)
+
+	var articles []models.Article
+	err := r.db.WithContext(ctx).Raw(pageSQL(inner, keyset, order, page), pageArgs(args, sort, page)...).Scan(&articles).Error
+	if err != nil {
+		return nil, nil, err
+	}
+
+	articles, next := trimPage(articles, page, sortKey(sort))
+	return articles, next, nil
 }
 
-func (r *ArticleRepository) GetTrendingByLocation(lat, lon, radiusKm float64, limit int, hoursBack int) ([]models.TrendingArticle, error) {
-	timeThreshold := time.Now().Add(-time.Duration(hoursBack) * time.Hour)
+// searchVector weights title matches above description matches. It must
+// match the expression of idx_articles_weighted_fts.
+const searchVector = `(setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(description, '')), 'B'))`
 
-	latDelta := radiusKm / 111.0
-	lonDelta := radiusKm / (111.0 * math.Cos(lat*math.Pi/180.0))
+const snippetOptions = "StartSel=<mark>, StopSel=</mark>, MaxWords=35, MinWords=15, MaxFragments=2"
 
-	minLat := lat - latDelta
-	maxLat := lat + latDelta
-	minLon := lon - lonDelta
-	maxLon := lon + lonDelta
+// SearchByText runs a Postgres full-text search. The query uses websearch
+// syntax, so "quoted phrases" and -exclusions work as users expect.
+func (r *ArticleRepository) SearchByText(ctx context.Context, text string, page models.PageRequest) ([]models.Article, *models.Cursor, error) {
+	inner := `
+            SELECT articles.*,
+                   ts_rank(` + searchVector + `, q)::float8 AS text_rank,
+                   ts_headline('english', description, q, ?) AS snippet
+            FROM articles, websearch_to_tsquery('english', ?) AS q
+            WHERE deleted_at IS NULL
+              AND ` + searchVector + ` @@ q`
 
-	type articleWithScore struct {
-		models.Article
-		WeightedScore    float64
-		HoursSinceLast   float64
-		InteractionCount int64
-	}
+	return r.listPage(ctx, inner, []interface{}{snippetOptions, text}, models.SortByTextRank, page)
+}
+
+// originSQL is the point given by its two parameters (lon, lat)
+const originSQL = `ST_SetSRID(ST_MakePoint(?, ?), 4326)::geography`
 
-	var articlesWithScores []articleWithScore
+// distanceSQL is the distance in km from the origin (lon, lat) to an
+// article, over the spheroid
+const distanceSQL = `ST_Distance(geog, ` + originSQL + `) / 1000`
 
-	query := `
-        WITH nearby_articles AS (
-            SELECT id, title, description, url, publication_date, source_name, 
-                   category, relevance_score, latitude, longitude
+// withinSQL matches articles within a distance of the origin given by its
+// parameters (lon, lat, meters), using the GiST index on geog
+const withinSQL = `ST_DWithin(geog, ` + originSQL + `, ?)`
+
+func (r *ArticleRepository) GetNearby(ctx context.Context, lat, lon, radiusKm float64, page models.PageRequest) ([]models.Article, *models.Cursor, error) {
+	// The radius is applied inside so the index narrows the rows before any
+	// distance is computed
+	inner := `
+            SELECT articles.*, ` + distanceSQL + ` AS distance
             FROM articles
             WHERE deleted_at IS NULL
-              AND latitude BETWEEN ? AND ?
-              AND longitude BETWEEN ? AND ?
-        ),
-        trending_scores AS (
-            SELECT 
-                ue.article_id,
-                COUNT(*) AS interaction_count,
-                SUM(CASE 
-                    WHEN ue.event_type = 'share' THEN 3.0
-                    WHEN ue.event_type = 'click' THEN 2.0
-                    WHEN ue.event_type = 'view' THEN 1.0
-                    ELSE 0.0
-                END) AS weighted_score,
-                EXTRACT(EPOCH FROM (NOW() - MAX(ue.timestamp))) / 3600.0 AS hours_since_last
-            FROM user_events ue
-            WHERE ue.timestamp > ?
-            GROUP BY ue.article_id
-        )
-        SELECT 
-            na.id, na.title, na.description, na.url, na.publication_date,
-            na.source_name, na.category, na.relevance_score, na.latitude, na.longitude,
-            COALESCE(ts.weighted_score, 0) AS weighted_score,
-            COALESCE(ts.hours_since_last, 999999) AS hours_since_last,
-            COALESCE(ts.interaction_count, 0) AS interaction_count
-        FROM nearby_articles na
-        LEFT JOIN trending_scores ts ON na.id = ts.article_id
-        ORDER BY 
-            CASE 
-                WHEN ts.weighted_score IS NOT NULL 
-                THEN ts.weighted_score / (1 + ts.hours_since_last)
-                ELSE 0 
-            END DESC,
-            na.publication_date DESC
-        LIMIT ?
-    `
-
-	err := r.db.Raw(query, minLat, maxLat, minLon, maxLon, timeThreshold, limit).Scan(&articlesWithScores).Error
+              AND ` + withinSQL
+
+	return r.listPage(ctx, inner, []interface{}{lon, lat, lon, lat, radiusKm * 1000}, models.SortByDistance, page)
+}
+
+func (r *ArticleRepository) CreateUserEvent(ctx context.Context, event *models.UserEvent) error {
+	return r.db.WithContext(ctx).Create(event).Error
+}
+
+// CreateUserEvents inserts events in bulk and reports for each whether it
+// was stored. Events whose idempotency key is already stored, or used by an
+// earlier event of the batch, are skipped.
+func (r *ArticleRepository) CreateUserEvents(ctx context.Context, events []models.UserEvent) ([]bool, error) {
+	var keys []string
+	for _, event := range events {
+		if event.IdempotencyKey != nil {
+			keys = append(keys, *event.IdempotencyKey)
+		}
+	}
+
+	existing, err := r.StoredIdempotencyKeys(ctx, keys)
 	if err != nil {
 		return nil, err
 	}
 
-	results := make([]models.TrendingArticle, len(articlesWithScores))
-	for i, aws := range articlesWithScores {
-		var trendingScore float64
-		if aws.WeightedScore > 0 {
-			trendingScore = (aws.WeightedScore * 100) / (1 + aws.HoursSinceLast)
+	stored := newByIdempotencyKey(events, existing)
+	var fresh []models.UserEvent
+	for i, event := range events {
+		if stored[i] {
+			fresh = append(fresh, event)
 		}
+	}
+	if len(fresh) == 0 {
+		return stored, nil
+	}
 
-		results[i] = models.TrendingArticle{
-			Article: models.Article{
-				ID:              aws.ID,
-				Title:           aws.Title,
-				Description:     aws.Description,
-				URL:             aws.URL,
-				PublicationDate: aws.PublicationDate,
-				SourceName:      aws.SourceName,
-				Category:        aws.Category,
-				RelevanceScore:  aws.RelevanceScore,
-				Latitude:        aws.Latitude,
-				Longitude:       aws.Longitude,
-			},
-			TrendingScore: trendingScore,
-		}
+	// A concurrent request storing the same key wins the race; the event is
+	// still stored once, so it is reported as stored here too
+	err = r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "idempotency_key"}},
+		DoNothing: true,
+	}).CreateInBatches(fresh, 500).Error
+	if err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
+
+// StoredIdempotencyKeys returns those of keys that stored events have
+func (r *ArticleRepository) StoredIdempotencyKeys(ctx context.Context, keys []string) ([]string, error) {
+	var existing []string
+	if len(keys) == 0 {
+		return existing, nil
 	}
+	err := r.db.WithContext(ctx).Model(&models.UserEvent{}).
+		Where("idempotency_key IN ?", keys).
+		Pluck("idempotency_key", &existing).Error
+	return existing, err
+}
+
+// DeleteUserEvents removes every event of a user and returns how many
+func (r *ArticleRepository) DeleteUserEvents(ctx context.Context, userID string) (int64, error) {
+	result := r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&models.UserEvent{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *ArticleRepository) GetTrendingByLocation(ctx context.Context, query models.TrendingQuery) ([]models.TrendingArticle, error) {
+	scoredSQL, scoredArgs := liveTrendingSQL(query, time.Now(), withinSQL, []interface{}{query.Lon, query.Lat, query.RadiusKm * 1000})
+
+	args := append(nearbyArticlesArgs(query), scoredArgs...)
+	args = append(args, query.Limit)
+
+	var rows []trendingRow
+	if err := r.db.WithContext(ctx).Raw(`WITH `+nearbyArticlesSQL+`, `+scoredSQL+trendingSelectSQL, args...).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	return toTrendingArticles(rows), nil
+}
+
+// ScoreTrendingWithin scores every article inside any of boxes that had
+// events in the query window, for materializing trending scores. Only the
+// events of those articles are read.
+func (r *ArticleRepository) ScoreTrendingWithin(ctx context.Context, boxes []models.BoundingBox, query models.TrendingQuery) ([]models.TrendingArticle, error) {
+	if len(boxes) == 0 {
+		return nil, nil
+	}
+
+	conditions := make([]string, len(boxes))
+	var areaArgs []interface{}
+	for i := range boxes {
+		condition, args := withinAreaSQL(models.GeoArea{Box: &boxes[i]})
+		conditions[i] = condition
+		areaArgs = append(areaArgs, args...)
+	}
+	scoredSQL, args := liveTrendingSQL(query, time.Now(), "("+strings.Join(conditions, " OR ")+")", areaArgs)
 
-	return results, nil
+	var rows []trendingRow
+	err := r.db.WithContext(ctx).Raw(`WITH `+scoredSQL+`
+        SELECT a.id, a.latitude, a.longitude, s.trending_score, s.interaction_count
+        FROM scored s
+        JOIN articles a ON a.id = s.article_id`, args...).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	return toTrendingArticles(rows), nil
 }
 
-func (r *ArticleRepository) GetAllCategories() ([]string, error) {
+// ListEventsCreatedAfter returns events stored after the event created at
+// createdAfter with ID afterID, in insert order, that happened after since
+func (r *ArticleRepository) ListEventsCreatedAfter(ctx context.Context, createdAfter time.Time, afterID uint, since time.Time, limit int) ([]models.UserEvent, error) {
+	var events []models.UserEvent
+	err := r.db.WithContext(ctx).
+		Where("(created_at, id) > (?, ?) AND timestamp > ?", createdAfter, afterID, since).
+		Order("created_at, id").
+		Limit(limit).
+		Find(&events).Error
+	return events, err
+}
+
+func (r *ArticleRepository) GetAllCategories(ctx context.Context) ([]string, error) {
 	var categories []string
-	err := r.db.Raw("SELECT DISTINCT unnest(category) FROM articles ORDER BY 1").Scan(&categories).Error
+	err := r.db.WithContext(ctx).Raw("SELECT DISTINCT unnest(category) FROM articles ORDER BY 1").Scan(&categories).Error
 	return categories, err
 }
 
-func (r *ArticleRepository) GetAllSources() ([]string, error) {
+func (r *ArticleRepository) GetAllSources(ctx context.Context) ([]string, error) {
 	var sources []string
-	err := r.db.Model(&models.Article{}).Distinct("source_name").Pluck("source_name", &sources).Error
+	err := r.db.WithContext(ctx).Model(&models.Article{}).Distinct("source_name").Pluck("source_name", &sources).Error
 	return sources, err
 }
 
-func (r *ArticleRepository) Count() (int64, error) {
+func (r *ArticleRepository) Count(ctx context.Context) (int64, error) {
 	var count int64
-	err := r.db.Model(&models.Article{}).Count(&count).Error
+	err := r.db.WithContext(ctx).Model(&models.Article{}).Count(&count).Error
 	return count, err
 }
+
+// newByIdempotencyKey reports which events have no key or a key that is
+// neither in existing nor used by an earlier event
+func newByIdempotencyKey(events []models.UserEvent, existing []string) []bool {
+	seen := make(map[string]bool, len(existing))
+	for _, key := range existing {
+		seen[key] = true
+	}
+
+	fresh := make([]bool, len(events))
+	for i, event := range events {
+		if event.IdempotencyKey == nil {
+			fresh[i] = true
+			continue
+		}
+		fresh[i] = !seen[*event.IdempotencyKey]
+		seen[*event.IdempotencyKey] = true
+	}
+	return fresh
+}
+
+// newByURL drops articles whose URL is in existing or repeats an earlier
+// article in the batch
+func newByURL(articles []models.Article, existing []string) []models.Article {
+	seen := make(map[string]bool, len(existing))
+	for _, url := range existing {
+		seen[url] = true
+	}
+
+	var fresh []models.Article
+	for _, article := range articles {
+		if seen[article.URL] {
+			continue
+		}
+		seen[article.URL] = true
+		fresh = append(fresh, article)
+	}
+	return fresh
+}
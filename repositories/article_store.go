@@ -0,0 +1,90 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"inshorts-news-api/models"
+)
+
+// ArticleStore is the storage behind ArticleService. ArticleRepository
+// implements it on Postgres, MemoryArticleStore in memory for tests.
+type ArticleStore interface {
+	Create(ctx context.Context, article *models.Article) error
+	BulkCreate(ctx context.Context, articles []models.Article) error
+	CreateNew(ctx context.Context, articles []models.Article) ([]models.Article, error)
+	StoredURLs(ctx context.Context, urls []string) ([]string, error)
+	GetByID(ctx context.Context, id string, includeDeleted bool) (*models.Article, error)
+	GetByIDs(ctx context.Context, ids []string) ([]models.Article, error)
+	Update(ctx context.Context, article *models.Article) error
+	Delete(ctx context.Context, id string) error
+	Restore(ctx context.Context, id string) error
+	CreateAuditLog(ctx context.Context, entry *models.ArticleAuditLog) error
+	Transaction(ctx context.Context, fn func(store ArticleStore) error) error
+	ListAfterID(ctx context.Context, afterID string, limit int) ([]models.Article, error)
+	ListPublishedBetween(ctx context.Context, from, to time.Time) ([]models.Article, error)
+	SetStoryIDs(ctx context.Context, storyIDs map[string]string) error
+	GetByStory(ctx context.Context, storyID string) ([]models.Article, error)
+
+	GetByCategory(ctx context.Context, category string, page models.PageRequest) ([]models.Article, *models.Cursor, error)
+	GetBySource(ctx context.Context, source string, page models.PageRequest) ([]models.Article, *models.Cursor, error)
+	GetByScore(ctx context.Context, minScore float64, page models.PageRequest) ([]models.Article, *models.Cursor, error)
+	SearchByText(ctx context.Context, text string, page models.PageRequest) ([]models.Article, *models.Cursor, error)
+	GetNearby(ctx context.Context, lat, lon, radiusKm float64, page models.PageRequest) ([]models.Article, *models.Cursor, error)
+	FindArticles(ctx context.Context, filter models.ArticleFilter, page models.PageRequest) ([]models.Article, *models.Cursor, error)
+	ClusterWithin(ctx context.Context, area models.GeoArea, zoom int) ([]models.MarkerCluster, error)
+
+	CreateUserEvent(ctx context.Context, event *models.UserEvent) error
+	CreateUserEvents(ctx context.Context, events []models.UserEvent) ([]bool, error)
+	StoredIdempotencyKeys(ctx context.Context, keys []string) ([]string, error)
+	DeleteUserEvents(ctx context.Context, userID string) (int64, error)
+	GetTrendingByLocation(ctx context.Context, query models.TrendingQuery) ([]models.TrendingArticle, error)
+	ScoreTrendingWithin(ctx context.Context, boxes []models.BoundingBox, query models.TrendingQuery) ([]models.TrendingArticle, error)
+	ListEventsCreatedAfter(ctx context.Context, createdAfter time.Time, afterID uint, since time.Time, limit int) ([]models.UserEvent, error)
+
+	GetAllCategories(ctx context.Context) ([]string, error)
+	GetAllSources(ctx context.Context) ([]string, error)
+	Count(ctx context.Context) (int64, error)
+}
+
+// SummaryStore caches generated article summaries
+type SummaryStore interface {
+	GetByArticleIDs(ctx context.Context, articleIDs []string, model string) ([]models.ArticleSummary, error)
+	Upsert(ctx context.Context, summaries []models.ArticleSummary) error
+}
+
+// EmbeddingStore keeps article embeddings for semantic search
+type EmbeddingStore interface {
+	GetByArticleIDs(ctx context.Context, articleIDs []string, model string) ([]models.ArticleEmbedding, error)
+	ListAfterArticleID(ctx context.Context, model, afterArticleID string, limit int) ([]models.ArticleEmbedding, error)
+	Upsert(ctx context.Context, embeddings []models.ArticleEmbedding) error
+}
+
+// FeedStore keeps the fetch status of ingested feeds
+type FeedStore interface {
+	GetStatus(ctx context.Context, feedURL string) (*models.FeedStatus, error)
+	SaveStatus(ctx context.Context, status *models.FeedStatus) error
+	ListStatuses(ctx context.Context) ([]models.FeedStatus, error)
+}
+
+// TrendingStore keeps materialized trending scores. Scores are replaced a
+// grid cell at a time, for one window length and algorithm.
+type TrendingStore interface {
+	ReplaceScores(ctx context.Context, cell string, windowHours int, algorithm string, scores []models.TrendingScore, refreshedAt time.Time) error
+	GetCells(ctx context.Context, cells []string, windowHours int, algorithm string) (map[string]models.TrendingCell, error)
+	ListCellsRefreshedBefore(ctx context.Context, before time.Time) ([]models.TrendingCell, error)
+	GetTrending(ctx context.Context, query models.TrendingQuery) ([]models.TrendingArticle, error)
+}
+
+var (
+	_ ArticleStore   = (*ArticleRepository)(nil)
+	_ ArticleStore   = (*MemoryArticleStore)(nil)
+	_ SummaryStore   = (*SummaryRepository)(nil)
+	_ SummaryStore   = (*MemorySummaryStore)(nil)
+	_ EmbeddingStore = (*EmbeddingRepository)(nil)
+	_ EmbeddingStore = (*MemoryEmbeddingStore)(nil)
+	_ FeedStore      = (*FeedRepository)(nil)
+	_ FeedStore      = (*MemoryFeedStore)(nil)
+	_ TrendingStore  = (*TrendingRepository)(nil)
+	_ TrendingStore  = (*MemoryTrendingStore)(nil)
+)
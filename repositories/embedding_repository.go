@@ -0,0 +1,53 @@
+package repositories
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"inshorts-news-api/models"
+)
+
+type EmbeddingRepository struct {
+	db *gorm.DB
+}
+
+func NewEmbeddingRepository(db *gorm.DB) *EmbeddingRepository {
+	return &EmbeddingRepository{db: db}
+}
+
+func (r *EmbeddingRepository) GetByArticleIDs(ctx context.Context, articleIDs []string, model string) ([]models.ArticleEmbedding, error) {
+	var embeddings []models.ArticleEmbedding
+	if len(articleIDs) == 0 {
+		return embeddings, nil
+	}
+
+	err := r.db.WithContext(ctx).Where("article_id IN ? AND model = ?", articleIDs, model).
+		Find(&embeddings).Error
+	return embeddings, err
+}
+
+// ListAfterArticleID pages through every embedding of a model in article
+// ID order, which is how the in-process index is loaded
+func (r *EmbeddingRepository) ListAfterArticleID(ctx context.Context, model, afterArticleID string, limit int) ([]models.ArticleEmbedding, error) {
+	var embeddings []models.ArticleEmbedding
+	err := r.db.WithContext(ctx).Where("model = ? AND article_id > ?", model, afterArticleID).
+		Order("article_id").
+		Limit(limit).
+		Find(&embeddings).Error
+	return embeddings, err
+}
+
+// Upsert stores embeddings, replacing any older embedding for the same
+// article and model
+func (r *EmbeddingRepository) Upsert(ctx context.Context, embeddings []models.ArticleEmbedding) error {
+	if len(embeddings) == 0 {
+		return nil
+	}
+
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "article_id"}, {Name: "model"}},
+		DoUpdates: clause.AssignmentColumns([]string{"content_hash", "vector", "updated_at"}),
+	}).Create(&embeddings).Error
+}
@@ -0,0 +1,41 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+
+	"inshorts-news-api/models"
+)
+
+type FeedRepository struct {
+	db *gorm.DB
+}
+
+func NewFeedRepository(db *gorm.DB) *FeedRepository {
+	return &FeedRepository{db: db}
+}
+
+// GetStatus returns nil without error for a feed that was never fetched
+func (r *FeedRepository) GetStatus(ctx context.Context, feedURL string) (*models.FeedStatus, error) {
+	var status models.FeedStatus
+	err := r.db.WithContext(ctx).Where("feed_url = ?", feedURL).First(&status).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+func (r *FeedRepository) SaveStatus(ctx context.Context, status *models.FeedStatus) error {
+	return r.db.WithContext(ctx).Save(status).Error
+}
+
+func (r *FeedRepository) ListStatuses(ctx context.Context) ([]models.FeedStatus, error) {
+	var statuses []models.FeedStatus
+	err := r.db.WithContext(ctx).Order("feed_url").Find(&statuses).Error
+	return statuses, err
+}
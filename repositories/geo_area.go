@@ -0,0 +1,84 @@
+package repositories
+
+import (
+	"context"
+	"math"
+	"sort"
+
+	"inshorts-news-api/models"
+)
+
+// withinAreaSQL matches articles inside a box or polygon. It compares in
+// lon/lat like GeoJSON does, through the geometry index on geog.
+func withinAreaSQL(area models.GeoArea) (string, []interface{}) {
+	if area.Box != nil {
+		b := area.Box
+		return "geog::geometry && ST_MakeEnvelope(?, ?, ?, ?, 4326)", []interface{}{b.MinLon, b.MinLat, b.MaxLon, b.MaxLat}
+	}
+	return "ST_Covers(ST_SetSRID(ST_GeomFromGeoJSON(?), 4326), geog::geometry)", []interface{}{area.Polygon.GeoJSON()}
+}
+
+// ClusterWithin groups every article inside area into grid cells sized for
+// the zoom level
+func (r *ArticleRepository) ClusterWithin(ctx context.Context, area models.GeoArea, zoom int) ([]models.MarkerCluster, error) {
+	if err := area.Validate(); err != nil {
+		return nil, err
+	}
+
+	condition, args := withinAreaSQL(area)
+	size := models.GridCellSize(zoom)
+
+	var cells []gridCell
+	query := `
+        SELECT floor(longitude / ?) AS x, floor(latitude / ?) AS y,
+               COUNT(*) AS count, AVG(latitude) AS latitude, AVG(longitude) AS longitude
+        FROM articles
+        WHERE deleted_at IS NULL
+          AND ` + condition + `
+        GROUP BY 1, 2
+    `
+	err := r.db.WithContext(ctx).Raw(query, append([]interface{}{size, size}, args...)...).Scan(&cells).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return toClusters(cells, size), nil
+}
+
+// gridCell is one occupied cell; X and Y count cells from lon/lat 0
+type gridCell struct {
+	X, Y      float64
+	Count     int64
+	Latitude  float64
+	Longitude float64
+}
+
+// toClusters orders clusters from the largest, then south to north and west
+// to east so responses are stable
+func toClusters(cells []gridCell, size float64) []models.MarkerCluster {
+	sort.Slice(cells, func(i, j int) bool {
+		if cells[i].Count != cells[j].Count {
+			return cells[i].Count > cells[j].Count
+		}
+		if cells[i].Y != cells[j].Y {
+			return cells[i].Y < cells[j].Y
+		}
+		return cells[i].X < cells[j].X
+	})
+
+	clusters := make([]models.MarkerCluster, len(cells))
+	for i, cell := range cells {
+		clusters[i] = models.MarkerCluster{
+			Latitude:  cell.Latitude,
+			Longitude: cell.Longitude,
+			Count:     cell.Count,
+			Bounds: models.BoundingBox{
+				MinLat: math.Max(cell.Y*size, -90),
+				MinLon: cell.X * size,
+				MaxLat: math.Min((cell.Y+1)*size, 90),
+				MaxLon: (cell.X + 1) * size,
+			},
+		}
+	}
+	return clusters
+}
@@ -0,0 +1,906 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+
+	"inshorts-news-api/models"
+	"inshorts-news-api/utils"
+)
+
+// MemoryArticleStore keeps articles and events in memory. It reproduces the
+// filtering, ordering and pagination of ArticleRepository so services and
+// handlers can be tested without Postgres.
+type MemoryArticleStore struct {
+	mu        sync.RWMutex
+	articles  map[string]models.Article
+	events    []models.UserEvent
+	auditLogs []models.ArticleAuditLog
+	nextID    uint
+}
+
+func NewMemoryArticleStore() *MemoryArticleStore {
+	return &MemoryArticleStore{
+		articles: make(map[string]models.Article),
+	}
+}
+
+func (s *MemoryArticleStore) Create(ctx context.Context, article *models.Article) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.articles[article.ID]; exists {
+		return fmt.Errorf("article %s already exists", article.ID)
+	}
+
+	now := time.Now()
+	article.CreatedAt = now
+	article.UpdatedAt = now
+	s.articles[article.ID] = *article
+	return nil
+}
+
+func (s *MemoryArticleStore) BulkCreate(ctx context.Context, articles []models.Article) error {
+	for i := range articles {
+		if err := s.Create(ctx, &articles[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *MemoryArticleStore) CreateNew(ctx context.Context, articles []models.Article) ([]models.Article, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var existing []string
+	for _, article := range s.articles {
+		existing = append(existing, article.URL)
+	}
+
+	var inserted []models.Article
+	now := time.Now()
+	for _, article := range newByURL(articles, existing) {
+		if _, exists := s.articles[article.ID]; exists {
+			continue
+		}
+		article.CreatedAt = now
+		article.UpdatedAt = now
+		s.articles[article.ID] = article
+		inserted = append(inserted, article)
+	}
+	return inserted, nil
+}
+
+func (s *MemoryArticleStore) StoredURLs(ctx context.Context, urls []string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	wanted := make(map[string]bool, len(urls))
+	for _, url := range urls {
+		wanted[url] = true
+	}
+
+	var existing []string
+	for _, article := range s.articles {
+		if wanted[article.URL] {
+			existing = append(existing, article.URL)
+		}
+	}
+	return existing, nil
+}
+
+func (s *MemoryArticleStore) GetByID(ctx context.Context, id string, includeDeleted bool) (*models.Article, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	article, ok := s.articles[id]
+	if !ok || (article.DeletedAt.Valid && !includeDeleted) {
+		return nil, ErrArticleNotFound
+	}
+	return &article, nil
+}
+
+func (s *MemoryArticleStore) GetByIDs(ctx context.Context, ids []string) ([]models.Article, error) {
+	wanted := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+
+	return s.filter(func(a models.Article) bool {
+		return wanted[a.ID]
+	}), nil
+}
+
+func (s *MemoryArticleStore) Update(ctx context.Context, article *models.Article) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.articles[article.ID]
+	if !ok || existing.DeletedAt.Valid {
+		return ErrArticleNotFound
+	}
+
+	article.CreatedAt = existing.CreatedAt
+	article.DeletedAt = existing.DeletedAt
+	article.UpdatedAt = time.Now()
+	s.articles[article.ID] = *article
+	return nil
+}
+
+func (s *MemoryArticleStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	article, ok := s.articles[id]
+	if !ok || article.DeletedAt.Valid {
+		return ErrArticleNotFound
+	}
+
+	article.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	s.articles[id] = article
+	return nil
+}
+
+func (s *MemoryArticleStore) Restore(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	article, ok := s.articles[id]
+	if !ok || !article.DeletedAt.Valid {
+		return ErrArticleNotFound
+	}
+
+	article.DeletedAt = gorm.DeletedAt{}
+	s.articles[id] = article
+	return nil
+}
+
+func (s *MemoryArticleStore) CreateAuditLog(ctx context.Context, entry *models.ArticleAuditLog) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry.ID = uint(len(s.auditLogs) + 1)
+	entry.CreatedAt = time.Now()
+	s.auditLogs = append(s.auditLogs, *entry)
+	return nil
+}
+
+// Transaction runs fn against the store and, if it fails, puts back the
+// state from before it ran. Writes from other goroutines while fn runs are
+// rolled back with it, which is acceptable for tests.
+func (s *MemoryArticleStore) Transaction(ctx context.Context, fn func(store ArticleStore) error) error {
+	s.mu.RLock()
+	articles := make(map[string]models.Article, len(s.articles))
+	for id, article := range s.articles {
+		articles[id] = article
+	}
+	events, auditLogs, nextID := slices.Clone(s.events), slices.Clone(s.auditLogs), s.nextID
+	s.mu.RUnlock()
+
+	if err := fn(s); err != nil {
+		s.mu.Lock()
+		s.articles, s.events, s.auditLogs, s.nextID = articles, events, auditLogs, nextID
+		s.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+func (s *MemoryArticleStore) ListAfterID(ctx context.Context, afterID string, limit int) ([]models.Article, error) {
+	articles := s.filter(func(a models.Article) bool {
+		return a.ID > afterID
+	})
+	sort.Slice(articles, func(i, j int) bool {
+		return articles[i].ID < articles[j].ID
+	})
+
+	if len(articles) > limit {
+		articles = articles[:limit]
+	}
+	return articles, nil
+}
+
+func (s *MemoryArticleStore) ListPublishedBetween(ctx context.Context, from, to time.Time) ([]models.Article, error) {
+	return s.filter(func(a models.Article) bool {
+		return !a.PublicationDate.Before(from) && !a.PublicationDate.After(to)
+	}), nil
+}
+
+func (s *MemoryArticleStore) SetStoryIDs(ctx context.Context, storyIDs map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, storyID := range storyIDs {
+		if article, ok := s.articles[id]; ok {
+			article.StoryID = storyID
+			s.articles[id] = article
+		}
+	}
+	return nil
+}
+
+func (s *MemoryArticleStore) GetByStory(ctx context.Context, storyID string) ([]models.Article, error) {
+	articles := s.filter(func(a models.Article) bool {
+		return a.StoryID == storyID
+	})
+	sort.Slice(articles, func(i, j int) bool {
+		return newestFirst(byPublicationDate(articles[j]), byPublicationDate(articles[i]))
+	})
+	return articles, nil
+}
+
+func (s *MemoryArticleStore) GetByCategory(ctx context.Context, category string, page models.PageRequest) ([]models.Article, *models.Cursor, error) {
+	articles := s.filter(func(a models.Article) bool {
+		for _, c := range a.Category {
+			if c == category {
+				return true
+			}
+		}
+		return false
+	})
+
+	articles, next := paginate(articles, page, byPublicationDate, newestFirst)
+	return articles, next, nil
+}
+
+func (s *MemoryArticleStore) GetBySource(ctx context.Context, source string, page models.PageRequest) ([]models.Article, *models.Cursor, error) {
+	source = strings.ToLower(source)
+	articles := s.filter(func(a models.Article) bool {
+		return strings.Contains(strings.ToLower(a.SourceName), source)
+	})
+
+	articles, next := paginate(articles, page, byPublicationDate, newestFirst)
+	return articles, next, nil
+}
+
+func (s *MemoryArticleStore) GetByScore(ctx context.Context, minScore float64, page models.PageRequest) ([]models.Article, *models.Cursor, error) {
+	articles := s.filter(func(a models.Article) bool {
+		return a.RelevanceScore >= minScore
+	})
+
+	articles, next := paginate(articles, page, byRelevanceScore, highestFirst)
+	return articles, next, nil
+}
+
+// SearchByText approximates websearch_to_tsquery: every word must match
+// (by prefix, standing in for stemming), "quoted phrases" must appear
+// verbatim and -excluded words must not appear. Title hits rank above
+// description hits, as with the A/B weights in Postgres.
+func (s *MemoryArticleStore) SearchByText(ctx context.Context, text string, page models.PageRequest) ([]models.Article, *models.Cursor, error) {
+	query := parseTextQuery(text)
+	if query.empty() {
+		return []models.Article{}, nil, nil
+	}
+
+	articles := s.filter(query.matches)
+	for i := range articles {
+		articles[i].TextRank = query.rank(articles[i])
+		articles[i].Snippet = query.highlight(articles[i].Description)
+	}
+
+	articles, next := paginate(articles, page, byTextRank, highestFirst)
+	return articles, next, nil
+}
+
+func (s *MemoryArticleStore) GetNearby(ctx context.Context, lat, lon, radiusKm float64, page models.PageRequest) ([]models.Article, *models.Cursor, error) {
+	articles := s.withinRadius(s.filter(func(models.Article) bool { return true }), models.GeoFilter{
+		Lat:      lat,
+		Lon:      lon,
+		RadiusKm: radiusKm,
+	})
+
+	articles, next := paginate(articles, page, byDistance, lowestFirst)
+	return articles, next, nil
+}
+
+func (s *MemoryArticleStore) FindArticles(ctx context.Context, filter models.ArticleFilter, page models.PageRequest) ([]models.Article, *models.Cursor, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	var query textQuery
+	if filter.Text != "" {
+		query = parseTextQuery(filter.Text)
+		if query.empty() {
+			return []models.Article{}, nil, nil
+		}
+	}
+
+	articles := s.filter(func(a models.Article) bool {
+		if len(filter.Categories) > 0 && !hasAnyCategory(a, filter.Categories) {
+			return false
+		}
+		if len(filter.Sources) > 0 && !hasAnySource(a, filter.Sources) {
+			return false
+		}
+		if filter.MinScore != nil && a.RelevanceScore < *filter.MinScore {
+			return false
+		}
+		if filter.MaxScore != nil && a.RelevanceScore > *filter.MaxScore {
+			return false
+		}
+		if filter.From != nil && a.PublicationDate.Before(*filter.From) {
+			return false
+		}
+		if filter.To != nil && a.PublicationDate.After(*filter.To) {
+			return false
+		}
+		if filter.Within != nil && !filter.Within.Contains(a.Latitude, a.Longitude) {
+			return false
+		}
+		if filter.Text != "" && !query.matches(a) {
+			return false
+		}
+		return true
+	})
+
+	if filter.Text != "" {
+		for i := range articles {
+			articles[i].TextRank = query.rank(articles[i])
+			articles[i].Snippet = query.highlight(articles[i].Description)
+		}
+	}
+
+	if filter.Near != nil {
+		articles = s.withinRadius(articles, *filter.Near)
+	}
+
+	articles, next := paginate(articles, page, sortKey(filter.Sort), sortsBefore(filter.Sort))
+	return articles, next, nil
+}
+
+func (s *MemoryArticleStore) ClusterWithin(ctx context.Context, area models.GeoArea, zoom int) ([]models.MarkerCluster, error) {
+	if err := area.Validate(); err != nil {
+		return nil, err
+	}
+
+	articles := s.filter(func(a models.Article) bool {
+		return area.Contains(a.Latitude, a.Longitude)
+	})
+	size := models.GridCellSize(zoom)
+
+	type cellKey struct{ x, y float64 }
+	byCell := make(map[cellKey]*gridCell)
+	for _, a := range articles {
+		key := cellKey{math.Floor(a.Longitude / size), math.Floor(a.Latitude / size)}
+		cell, ok := byCell[key]
+		if !ok {
+			cell = &gridCell{X: key.x, Y: key.y}
+			byCell[key] = cell
+		}
+		cell.Count++
+		cell.Latitude += a.Latitude
+		cell.Longitude += a.Longitude
+	}
+
+	cells := make([]gridCell, 0, len(byCell))
+	for _, cell := range byCell {
+		cell.Latitude /= float64(cell.Count)
+		cell.Longitude /= float64(cell.Count)
+		cells = append(cells, *cell)
+	}
+
+	return toClusters(cells, size), nil
+}
+
+func (s *MemoryArticleStore) CreateUserEvent(ctx context.Context, event *models.UserEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	event.ID = s.nextID
+	event.CreatedAt = time.Now()
+	s.events = append(s.events, *event)
+	return nil
+}
+
+func (s *MemoryArticleStore) CreateUserEvents(ctx context.Context, events []models.UserEvent) ([]bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var existing []string
+	for _, event := range s.events {
+		if event.IdempotencyKey != nil {
+			existing = append(existing, *event.IdempotencyKey)
+		}
+	}
+
+	stored := newByIdempotencyKey(events, existing)
+	now := time.Now()
+	for i, event := range events {
+		if !stored[i] {
+			continue
+		}
+		s.nextID++
+		event.ID = s.nextID
+		event.CreatedAt = now
+		s.events = append(s.events, event)
+	}
+	return stored, nil
+}
+
+func (s *MemoryArticleStore) StoredIdempotencyKeys(ctx context.Context, keys []string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	wanted := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		wanted[key] = true
+	}
+
+	var existing []string
+	for _, event := range s.events {
+		if event.IdempotencyKey != nil && wanted[*event.IdempotencyKey] {
+			existing = append(existing, *event.IdempotencyKey)
+		}
+	}
+	return existing, nil
+}
+
+func (s *MemoryArticleStore) DeleteUserEvents(ctx context.Context, userID string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.events[:0]
+	for _, event := range s.events {
+		if event.UserID != userID {
+			kept = append(kept, event)
+		}
+	}
+	deleted := int64(len(s.events) - len(kept))
+	s.events = kept
+	return deleted, nil
+}
+
+func (s *MemoryArticleStore) GetTrendingByLocation(ctx context.Context, query models.TrendingQuery) ([]models.TrendingArticle, error) {
+	articles := s.withinRadius(s.filter(func(models.Article) bool { return true }), models.GeoFilter{
+		Lat:      query.Lat,
+		Lon:      query.Lon,
+		RadiusKm: query.RadiusKm,
+	})
+	stats := s.trendingStats(query, time.Now())
+
+	results := make([]models.TrendingArticle, len(articles))
+	for i, article := range articles {
+		results[i] = models.TrendingArticle{Article: article}
+		if st, ok := stats[article.ID]; ok {
+			results[i].TrendingScore = trendingScore(query.TrendingOptions, *st)
+			results[i].InteractionCount = st.Count
+		}
+	}
+
+	sortTrending(results)
+	if len(results) > query.Limit {
+		results = results[:query.Limit]
+	}
+
+	return results, nil
+}
+
+func (s *MemoryArticleStore) ScoreTrendingWithin(ctx context.Context, boxes []models.BoundingBox, query models.TrendingQuery) ([]models.TrendingArticle, error) {
+	stats := s.trendingStats(query, time.Now())
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var results []models.TrendingArticle
+	for id, st := range stats {
+		article, ok := s.articles[id]
+		if !ok || article.DeletedAt.Valid || !slices.ContainsFunc(boxes, func(box models.BoundingBox) bool {
+			return box.Contains(article.Latitude, article.Longitude)
+		}) {
+			continue
+		}
+		results = append(results, models.TrendingArticle{
+			Article:          article,
+			TrendingScore:    trendingScore(query.TrendingOptions, *st),
+			InteractionCount: st.Count,
+		})
+	}
+	return results, nil
+}
+
+func (s *MemoryArticleStore) ListEventsCreatedAfter(ctx context.Context, createdAfter time.Time, afterID uint, since time.Time, limit int) ([]models.UserEvent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var events []models.UserEvent
+	for _, event := range s.events {
+		after := event.CreatedAt.After(createdAfter) || (event.CreatedAt.Equal(createdAfter) && event.ID > afterID)
+		if after && event.Timestamp.After(since) {
+			events = append(events, event)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if !events[i].CreatedAt.Equal(events[j].CreatedAt) {
+			return events[i].CreatedAt.Before(events[j].CreatedAt)
+		}
+		return events[i].ID < events[j].ID
+	})
+	if len(events) > limit {
+		events = events[:limit]
+	}
+	return events, nil
+}
+
+// trendingStats aggregates the events of the query window per article, and
+// those of the window before for velocity, leaving out suspicious events.
+// It mirrors liveTrendingSQL, including counting unique users.
+func (s *MemoryArticleStore) trendingStats(query models.TrendingQuery, now time.Time) map[string]*eventStats {
+	since := now.Add(-query.Window)
+	previousSince := since
+	if query.Algorithm == models.TrendingVelocity {
+		previousSince = since.Add(-query.Window)
+	}
+
+	type countedKey struct {
+		articleID string
+		actor     string
+		eventType string
+		inWindow  bool
+	}
+
+	s.mu.RLock()
+	var counted []models.UserEvent
+	latest := make(map[countedKey]int)
+	for _, event := range s.events {
+		if !event.Timestamp.After(previousSince) || event.Suspicious {
+			continue
+		}
+		if !query.UniqueUsers {
+			counted = append(counted, event)
+			continue
+		}
+		key := countedKey{event.ArticleID, eventActor(event), event.EventType, event.Timestamp.After(since)}
+		if i, ok := latest[key]; !ok {
+			latest[key] = len(counted)
+			counted = append(counted, event)
+		} else if event.Timestamp.After(counted[i].Timestamp) {
+			counted[i] = event
+		}
+	}
+	s.mu.RUnlock()
+
+	stats := make(map[string]*eventStats)
+	actors := make(map[string]map[string]bool)
+	for _, event := range counted {
+		st, ok := stats[event.ArticleID]
+		if !ok {
+			st = &eventStats{}
+			stats[event.ArticleID] = st
+			actors[event.ArticleID] = make(map[string]bool)
+		}
+		st.add(query.Weights[event.EventType], now.Sub(event.Timestamp), query.Window, query.HalfLife)
+		if event.Timestamp.After(since) {
+			actors[event.ArticleID][eventActor(event)] = true
+		}
+	}
+
+	if query.UniqueUsers {
+		for articleID, st := range stats {
+			st.Count = int64(len(actors[articleID]))
+		}
+	}
+	return stats
+}
+
+// sortTrending orders articles like the trending SQL: by score, then newest
+// first
+func sortTrending(results []models.TrendingArticle) {
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].TrendingScore != results[j].TrendingScore {
+			return results[i].TrendingScore > results[j].TrendingScore
+		}
+		return results[i].PublicationDate.After(results[j].PublicationDate)
+	})
+}
+
+// GetAllCategories includes soft-deleted articles, like the raw SQL it mirrors
+func (s *MemoryArticleStore) GetAllCategories(ctx context.Context) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	categories := []string{}
+	for _, article := range s.articles {
+		for _, c := range article.Category {
+			if !seen[c] {
+				seen[c] = true
+				categories = append(categories, c)
+			}
+		}
+	}
+
+	sort.Strings(categories)
+	return categories, nil
+}
+
+func (s *MemoryArticleStore) GetAllSources(ctx context.Context) ([]string, error) {
+	seen := make(map[string]bool)
+	sources := []string{}
+	for _, article := range s.filter(func(models.Article) bool { return true }) {
+		if !seen[article.SourceName] {
+			seen[article.SourceName] = true
+			sources = append(sources, article.SourceName)
+		}
+	}
+	return sources, nil
+}
+
+func (s *MemoryArticleStore) Count(ctx context.Context) (int64, error) {
+	return int64(len(s.filter(func(models.Article) bool { return true }))), nil
+}
+
+// AuditLogs returns a copy of the recorded audit log entries
+func (s *MemoryArticleStore) AuditLogs() []models.ArticleAuditLog {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]models.ArticleAuditLog(nil), s.auditLogs...)
+}
+
+// Events returns a copy of the recorded user events
+func (s *MemoryArticleStore) Events() []models.UserEvent {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]models.UserEvent(nil), s.events...)
+}
+
+// withinRadius keeps the articles inside the radius and sets their distance
+func (s *MemoryArticleStore) withinRadius(articles []models.Article, near models.GeoFilter) []models.Article {
+	kept := articles[:0]
+	for _, a := range articles {
+		distance := utils.Haversine(near.Lat, near.Lon, a.Latitude, a.Longitude)
+		if distance <= near.RadiusKm {
+			a.Distance = &distance
+			kept = append(kept, a)
+		}
+	}
+	return kept
+}
+
+func hasAnyCategory(a models.Article, categories []string) bool {
+	for _, c := range a.Category {
+		for _, want := range categories {
+			if strings.EqualFold(c, want) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func hasAnySource(a models.Article, sources []string) bool {
+	name := strings.ToLower(a.SourceName)
+	for _, source := range sources {
+		if strings.Contains(name, strings.ToLower(source)) {
+			return true
+		}
+	}
+	return false
+}
+
+// filter returns copies of the live (not soft-deleted) articles accepted by keep
+func (s *MemoryArticleStore) filter(keep func(models.Article) bool) []models.Article {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	articles := []models.Article{}
+	for _, article := range s.articles {
+		if article.DeletedAt.Valid {
+			continue
+		}
+		if keep(article) {
+			articles = append(articles, article)
+		}
+	}
+	return articles
+}
+
+// before reports whether cursor a sorts ahead of cursor b
+type before func(a, b models.Cursor) bool
+
+func newestFirst(a, b models.Cursor) bool {
+	if !a.Time.Equal(b.Time) {
+		return a.Time.After(b.Time)
+	}
+	return a.ID > b.ID
+}
+
+func highestFirst(a, b models.Cursor) bool {
+	if a.Value != b.Value {
+		return a.Value > b.Value
+	}
+	return a.ID > b.ID
+}
+
+func lowestFirst(a, b models.Cursor) bool {
+	if a.Value != b.Value {
+		return a.Value < b.Value
+	}
+	return a.ID < b.ID
+}
+
+// sortsBefore mirrors the ORDER BY that sortClauses picks for a sort
+func sortsBefore(sort string) before {
+	switch sort {
+	case models.SortByRelevance, models.SortByTextRank:
+		return highestFirst
+	case models.SortByDistance:
+		return lowestFirst
+	default:
+		return newestFirst
+	}
+}
+
+// paginate sorts articles, collapses stories if asked, skips everything up
+// to the cursor and returns one page, the same way pageSQL does
+func paginate(articles []models.Article, page models.PageRequest, key func(models.Article) models.Cursor, less before) ([]models.Article, *models.Cursor) {
+	sort.Slice(articles, func(i, j int) bool {
+		return less(key(articles[i]), key(articles[j]))
+	})
+	if page.Collapse {
+		articles = models.CollapseStories(articles)
+	}
+
+	if page.Cursor != nil {
+		start := sort.Search(len(articles), func(i int) bool {
+			return less(*page.Cursor, key(articles[i]))
+		})
+		articles = articles[start:]
+	}
+
+	if limit := pageLimit(page); len(articles) > limit {
+		articles = articles[:limit]
+	}
+
+	return trimPage(articles, page, key)
+}
+
+type textQuery struct {
+	words    []string
+	phrases  []string
+	excluded []string
+}
+
+func parseTextQuery(text string) textQuery {
+	var q textQuery
+	text = strings.ToLower(text)
+
+	for len(text) > 0 {
+		text = strings.TrimLeft(text, " \t\n")
+		if text == "" {
+			break
+		}
+
+		negated := strings.HasPrefix(text, "-")
+		if negated {
+			text = text[1:]
+		}
+
+		var term string
+		if strings.HasPrefix(text, `"`) {
+			end := strings.Index(text[1:], `"`)
+			if end < 0 {
+				term, text = text[1:], ""
+			} else {
+				term, text = text[1:end+1], text[end+2:]
+			}
+		} else {
+			end := strings.IndexAny(text, " \t\n")
+			if end < 0 {
+				term, text = text, ""
+			} else {
+				term, text = text[:end], text[end:]
+			}
+		}
+
+		words := tokenize(term)
+		switch {
+		case len(words) == 0:
+		case negated:
+			q.excluded = append(q.excluded, words...)
+		case len(words) > 1:
+			q.phrases = append(q.phrases, strings.Join(words, " "))
+		default:
+			q.words = append(q.words, words[0])
+		}
+	}
+
+	return q
+}
+
+func (q textQuery) empty() bool {
+	return len(q.words) == 0 && len(q.phrases) == 0
+}
+
+func (q textQuery) matches(a models.Article) bool {
+	tokens := tokenize(a.Title + " " + a.Description)
+	joined := " " + strings.Join(tokens, " ") + " "
+
+	for _, word := range q.words {
+		if countPrefixMatches(tokens, word) == 0 {
+			return false
+		}
+	}
+	for _, phrase := range q.phrases {
+		if !strings.Contains(joined, " "+phrase+" ") {
+			return false
+		}
+	}
+	for _, word := range q.excluded {
+		if countPrefixMatches(tokens, word) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (q textQuery) rank(a models.Article) float64 {
+	title, description := tokenize(a.Title), tokenize(a.Description)
+	terms := append(append([]string{}, q.words...), q.phrases...)
+
+	var rank float64
+	for _, term := range terms {
+		for _, word := range strings.Fields(term) {
+			rank += 1.0 * float64(countPrefixMatches(title, word))
+			rank += 0.4 * float64(countPrefixMatches(description, word))
+		}
+	}
+	return rank / float64(len(title)+len(description)+1)
+}
+
+func (q textQuery) highlight(text string) string {
+	terms := append([]string{}, q.words...)
+	for _, phrase := range q.phrases {
+		terms = append(terms, strings.Fields(phrase)...)
+	}
+
+	words := strings.Fields(text)
+	for i, word := range words {
+		tokens := tokenize(word)
+		if len(tokens) == 1 && matchesAny(tokens[0], terms) {
+			words[i] = "<mark>" + word + "</mark>"
+		}
+	}
+	if len(words) > 35 {
+		words = words[:35]
+	}
+	return strings.Join(words, " ")
+}
+
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func countPrefixMatches(tokens []string, prefix string) int {
+	count := 0
+	for _, token := range tokens {
+		if strings.HasPrefix(token, prefix) {
+			count++
+		}
+	}
+	return count
+}
+
+func matchesAny(token string, terms []string) bool {
+	for _, term := range terms {
+		if strings.HasPrefix(token, term) {
+			return true
+		}
+	}
+	return false
+}
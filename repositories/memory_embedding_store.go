@@ -0,0 +1,75 @@
+package repositories
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"inshorts-news-api/models"
+)
+
+// MemoryEmbeddingStore is an in-memory EmbeddingStore for tests
+type MemoryEmbeddingStore struct {
+	mu         sync.RWMutex
+	embeddings map[string]models.ArticleEmbedding // keyed by article ID + model
+}
+
+func NewMemoryEmbeddingStore() *MemoryEmbeddingStore {
+	return &MemoryEmbeddingStore{
+		embeddings: make(map[string]models.ArticleEmbedding),
+	}
+}
+
+func (s *MemoryEmbeddingStore) GetByArticleIDs(ctx context.Context, articleIDs []string, model string) ([]models.ArticleEmbedding, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	embeddings := []models.ArticleEmbedding{}
+	for _, id := range articleIDs {
+		if embedding, ok := s.embeddings[id+"\x00"+model]; ok {
+			embeddings = append(embeddings, embedding)
+		}
+	}
+	return embeddings, nil
+}
+
+func (s *MemoryEmbeddingStore) ListAfterArticleID(ctx context.Context, model, afterArticleID string, limit int) ([]models.ArticleEmbedding, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	embeddings := []models.ArticleEmbedding{}
+	for _, embedding := range s.embeddings {
+		if embedding.Model == model && embedding.ArticleID > afterArticleID {
+			embeddings = append(embeddings, embedding)
+		}
+	}
+	sort.Slice(embeddings, func(i, j int) bool {
+		return embeddings[i].ArticleID < embeddings[j].ArticleID
+	})
+
+	if len(embeddings) > limit {
+		embeddings = embeddings[:limit]
+	}
+	return embeddings, nil
+}
+
+func (s *MemoryEmbeddingStore) Upsert(ctx context.Context, embeddings []models.ArticleEmbedding) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for _, embedding := range embeddings {
+		key := embedding.ArticleID + "\x00" + embedding.Model
+		if existing, ok := s.embeddings[key]; ok {
+			embedding.ID = existing.ID
+			embedding.CreatedAt = existing.CreatedAt
+		} else {
+			embedding.ID = uint(len(s.embeddings) + 1)
+			embedding.CreatedAt = now
+		}
+		embedding.UpdatedAt = now
+		s.embeddings[key] = embedding
+	}
+	return nil
+}
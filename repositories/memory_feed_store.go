@@ -0,0 +1,56 @@
+package repositories
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"inshorts-news-api/models"
+)
+
+// MemoryFeedStore is an in-memory FeedStore for tests
+type MemoryFeedStore struct {
+	mu       sync.RWMutex
+	statuses map[string]models.FeedStatus
+}
+
+func NewMemoryFeedStore() *MemoryFeedStore {
+	return &MemoryFeedStore{
+		statuses: make(map[string]models.FeedStatus),
+	}
+}
+
+func (s *MemoryFeedStore) GetStatus(ctx context.Context, feedURL string) (*models.FeedStatus, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	status, ok := s.statuses[feedURL]
+	if !ok {
+		return nil, nil
+	}
+	return &status, nil
+}
+
+func (s *MemoryFeedStore) SaveStatus(ctx context.Context, status *models.FeedStatus) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status.UpdatedAt = time.Now()
+	s.statuses[status.FeedURL] = *status
+	return nil
+}
+
+func (s *MemoryFeedStore) ListStatuses(ctx context.Context) ([]models.FeedStatus, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	statuses := make([]models.FeedStatus, 0, len(s.statuses))
+	for _, status := range s.statuses {
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].FeedURL < statuses[j].FeedURL
+	})
+	return statuses, nil
+}
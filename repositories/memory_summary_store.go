@@ -0,0 +1,54 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"inshorts-news-api/models"
+)
+
+// MemorySummaryStore is an in-memory SummaryStore for tests
+type MemorySummaryStore struct {
+	mu        sync.RWMutex
+	summaries map[string]models.ArticleSummary // keyed by article ID + model
+}
+
+func NewMemorySummaryStore() *MemorySummaryStore {
+	return &MemorySummaryStore{
+		summaries: make(map[string]models.ArticleSummary),
+	}
+}
+
+func (s *MemorySummaryStore) GetByArticleIDs(ctx context.Context, articleIDs []string, model string) ([]models.ArticleSummary, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	summaries := []models.ArticleSummary{}
+	for _, id := range articleIDs {
+		if summary, ok := s.summaries[id+"\x00"+model]; ok {
+			summaries = append(summaries, summary)
+		}
+	}
+	return summaries, nil
+}
+
+func (s *MemorySummaryStore) Upsert(ctx context.Context, summaries []models.ArticleSummary) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for _, summary := range summaries {
+		key := summary.ArticleID + "\x00" + summary.Model
+		if existing, ok := s.summaries[key]; ok {
+			summary.ID = existing.ID
+			summary.CreatedAt = existing.CreatedAt
+		} else {
+			summary.ID = uint(len(s.summaries) + 1)
+			summary.CreatedAt = now
+		}
+		summary.UpdatedAt = now
+		s.summaries[key] = summary
+	}
+	return nil
+}
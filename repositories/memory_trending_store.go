@@ -0,0 +1,115 @@
+package repositories
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"inshorts-news-api/models"
+)
+
+// MemoryTrendingStore is an in-memory TrendingStore for tests. It ranks
+// the articles of a MemoryArticleStore.
+type MemoryTrendingStore struct {
+	mu       sync.RWMutex
+	articles *MemoryArticleStore
+	scores   map[trendingScoreKey]models.TrendingScore
+	cells    map[trendingCellKey]models.TrendingCell
+}
+
+type trendingScoreKey struct {
+	articleID   string
+	windowHours int
+	algorithm   string
+}
+
+type trendingCellKey struct {
+	cell        string
+	windowHours int
+	algorithm   string
+}
+
+func NewMemoryTrendingStore(articles *MemoryArticleStore) *MemoryTrendingStore {
+	return &MemoryTrendingStore{
+		articles: articles,
+		scores:   make(map[trendingScoreKey]models.TrendingScore),
+		cells:    make(map[trendingCellKey]models.TrendingCell),
+	}
+}
+
+func (s *MemoryTrendingStore) ReplaceScores(ctx context.Context, cell string, windowHours int, algorithm string, scores []models.TrendingScore, refreshedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, score := range s.scores {
+		if score.Cell == cell && key.windowHours == windowHours && key.algorithm == algorithm {
+			delete(s.scores, key)
+		}
+	}
+
+	for _, score := range scores {
+		s.scores[trendingScoreKey{articleID: score.ArticleID, windowHours: score.WindowHours, algorithm: score.Algorithm}] = score
+	}
+	s.cells[trendingCellKey{cell: cell, windowHours: windowHours, algorithm: algorithm}] = models.TrendingCell{
+		Cell:        cell,
+		WindowHours: windowHours,
+		Algorithm:   algorithm,
+		RefreshedAt: refreshedAt,
+		Empty:       len(scores) == 0,
+	}
+	return nil
+}
+
+func (s *MemoryTrendingStore) GetCells(ctx context.Context, cells []string, windowHours int, algorithm string) (map[string]models.TrendingCell, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	found := make(map[string]models.TrendingCell, len(cells))
+	for _, cell := range cells {
+		if row, ok := s.cells[trendingCellKey{cell: cell, windowHours: windowHours, algorithm: algorithm}]; ok {
+			found[cell] = row
+		}
+	}
+	return found, nil
+}
+
+func (s *MemoryTrendingStore) ListCellsRefreshedBefore(ctx context.Context, before time.Time) ([]models.TrendingCell, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var cells []models.TrendingCell
+	for _, cell := range s.cells {
+		if cell.RefreshedAt.Before(before) && !cell.Empty {
+			cells = append(cells, cell)
+		}
+	}
+	sort.Slice(cells, func(i, j int) bool { return cells[i].RefreshedAt.Before(cells[j].RefreshedAt) })
+	return cells, nil
+}
+
+func (s *MemoryTrendingStore) GetTrending(ctx context.Context, query models.TrendingQuery) ([]models.TrendingArticle, error) {
+	articles := s.articles.withinRadius(s.articles.filter(func(models.Article) bool { return true }), models.GeoFilter{
+		Lat:      query.Lat,
+		Lon:      query.Lon,
+		RadiusKm: query.RadiusKm,
+	})
+
+	s.mu.RLock()
+	results := make([]models.TrendingArticle, len(articles))
+	for i, article := range articles {
+		results[i] = models.TrendingArticle{Article: article}
+		key := trendingScoreKey{articleID: article.ID, windowHours: int(query.Window.Hours()), algorithm: query.Algorithm}
+		if score, ok := s.scores[key]; ok {
+			results[i].TrendingScore = score.Score
+			results[i].InteractionCount = score.InteractionCount
+		}
+	}
+	s.mu.RUnlock()
+
+	sortTrending(results)
+	if len(results) > query.Limit {
+		results = results[:query.Limit]
+	}
+	return results, nil
+}
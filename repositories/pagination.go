@@ -0,0 +1,90 @@
+package repositories
+
+import (
+	"strings"
+
+	"inshorts-news-api/models"
+)
+
+// pageLimit fetches one row beyond the page size so we know whether
+// another page follows without a separate COUNT query.
+func pageLimit(page models.PageRequest) int {
+	return page.PageSize + 1
+}
+
+// storySQL is the story of an article; an article without one is the seed
+// of its own
+const storySQL = `COALESCE(NULLIF(story_id, ''), id)`
+
+// pageSQL pages through inner, a query of articles with the columns keyset
+// and order refer to; keyset applies when page has a cursor. Collapsing
+// keeps the first article of each story in that order, with the count of
+// the others as duplicates, before the keyset applies, so a story shows up
+// once across all pages. Its arguments are those of inner, then the cursor
+// and the limit.
+func pageSQL(inner, keyset, order string, page models.PageRequest) string {
+	query := `
+        SELECT * FROM (` + inner + `) AS page_articles`
+	var where []string
+	if page.Collapse {
+		query = `
+        SELECT * FROM (
+            SELECT *,
+                   row_number() OVER (PARTITION BY ` + storySQL + ` ORDER BY ` + order + `) AS story_rank,
+                   COUNT(*) OVER (PARTITION BY ` + storySQL + `) - 1 AS duplicates
+            FROM (` + inner + `) AS filtered_articles
+        ) AS page_articles`
+		where = append(where, "story_rank = 1")
+	}
+	if page.Cursor != nil {
+		where = append(where, keyset)
+	}
+	if len(where) > 0 {
+		query += "\n        WHERE " + strings.Join(where, " AND ")
+	}
+	return query + "\n        ORDER BY " + order + "\n        LIMIT ?"
+}
+
+// pageArgs appends the cursor of page, if any, and the limit to the
+// arguments of inner
+func pageArgs(args []interface{}, sort string, page models.PageRequest) []interface{} {
+	if page.Cursor != nil {
+		if sort == models.SortByDate {
+			args = append(args, page.Cursor.Time, page.Cursor.ID)
+		} else {
+			args = append(args, page.Cursor.Value, page.Cursor.ID)
+		}
+	}
+	return append(args, pageLimit(page))
+}
+
+// trimPage drops the look-ahead row and builds the cursor for the next page
+// from the last article that is actually returned.
+func trimPage(articles []models.Article, page models.PageRequest, key func(models.Article) models.Cursor) ([]models.Article, *models.Cursor) {
+	if len(articles) <= page.PageSize {
+		return articles, nil
+	}
+	articles = articles[:page.PageSize]
+	next := key(articles[len(articles)-1])
+	return articles, &next
+}
+
+func byPublicationDate(a models.Article) models.Cursor {
+	return models.Cursor{Time: a.PublicationDate, ID: a.ID}
+}
+
+func byRelevanceScore(a models.Article) models.Cursor {
+	return models.Cursor{Value: a.RelevanceScore, ID: a.ID}
+}
+
+func byTextRank(a models.Article) models.Cursor {
+	return models.Cursor{Value: a.TextRank, ID: a.ID}
+}
+
+func byDistance(a models.Article) models.Cursor {
+	var distance float64
+	if a.Distance != nil {
+		distance = *a.Distance
+	}
+	return models.Cursor{Value: distance, ID: a.ID}
+}
@@ -0,0 +1,42 @@
+package repositories
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"inshorts-news-api/models"
+)
+
+type SummaryRepository struct {
+	db *gorm.DB
+}
+
+func NewSummaryRepository(db *gorm.DB) *SummaryRepository {
+	return &SummaryRepository{db: db}
+}
+
+func (r *SummaryRepository) GetByArticleIDs(ctx context.Context, articleIDs []string, model string) ([]models.ArticleSummary, error) {
+	var summaries []models.ArticleSummary
+	if len(articleIDs) == 0 {
+		return summaries, nil
+	}
+
+	err := r.db.WithContext(ctx).Where("article_id IN ? AND model = ?", articleIDs, model).
+		Find(&summaries).Error
+	return summaries, err
+}
+
+// Upsert stores summaries, replacing any older summary for the same
+// article and model
+func (r *SummaryRepository) Upsert(ctx context.Context, summaries []models.ArticleSummary) error {
+	if len(summaries) == 0 {
+		return nil
+	}
+
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "article_id"}, {Name: "model"}},
+		DoUpdates: clause.AssignmentColumns([]string{"content_hash", "summary", "updated_at"}),
+	}).Create(&summaries).Error
+}
@@ -0,0 +1,224 @@
+package repositories
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"inshorts-news-api/models"
+)
+
+// eventStats are the aggregates of one article's events that every
+// trending algorithm is computed from. Current covers the query window,
+// Previous the window of the same length before it.
+type eventStats struct {
+	Count          int64
+	Current        float64
+	Previous       float64
+	Decayed        float64
+	HoursSinceLast float64
+}
+
+// add counts one event seen at age before now
+func (st *eventStats) add(weight float64, age, window, halfLife time.Duration) {
+	if age >= window {
+		st.Previous += weight
+		return
+	}
+
+	hours := age.Hours()
+	if st.Count == 0 || hours < st.HoursSinceLast {
+		st.HoursSinceLast = hours
+	}
+	st.Count++
+	st.Current += weight
+	st.Decayed += weight * math.Pow(0.5, hours/halfLife.Hours())
+}
+
+// trendingScore is the score reported to clients and ranked on. It mirrors
+// trendingScoreSQL.
+func trendingScore(options models.TrendingOptions, st eventStats) float64 {
+	if st.Count == 0 || st.Count < int64(options.MinInteractions) {
+		return 0
+	}
+
+	switch options.Algorithm {
+	case models.TrendingDecay:
+		return st.Decayed
+	case models.TrendingVelocity:
+		return st.Current - st.Previous
+	default:
+		return (st.Current * 100) / (1 + st.HoursSinceLast)
+	}
+}
+
+// nearbyArticlesSQL is a CTE of the articles within a radius of a point,
+// with their distance. Its arguments come from nearbyArticlesArgs.
+const nearbyArticlesSQL = `nearby_articles AS (
+            SELECT id, title, description, url, publication_date, source_name, 
+                   category, relevance_score, latitude, longitude,
+                   ` + distanceSQL + ` AS distance
+            FROM articles
+            WHERE deleted_at IS NULL
+              AND ` + withinSQL + `
+        )`
+
+func nearbyArticlesArgs(query models.TrendingQuery) []interface{} {
+	return []interface{}{query.Lon, query.Lat, query.Lon, query.Lat, query.RadiusKm * 1000}
+}
+
+// trendingSelectSQL ranks nearby_articles by the trending_score of the
+// scored CTE; its one argument is the limit
+const trendingSelectSQL = `
+        SELECT 
+            na.id, na.title, na.description, na.url, na.publication_date,
+            na.source_name, na.category, na.relevance_score, na.latitude, na.longitude, na.distance,
+            COALESCE(s.trending_score, 0) AS trending_score,
+            COALESCE(s.interaction_count, 0) AS interaction_count
+        FROM nearby_articles na
+        LEFT JOIN scored s ON na.id = s.article_id
+        ORDER BY trending_score DESC, na.publication_date DESC
+        LIMIT ?
+    `
+
+// actorSQL identifies who caused an event: the user, else the device.
+// Anonymous events each count as their own actor.
+const actorSQL = `COALESCE(NULLIF(ue.user_id, ''), NULLIF(ue.device_id, ''), 'event:' || ue.id::text)`
+
+// liveTrendingSQL builds CTEs ending in scored, the trending score and
+// interaction count of each article with events in the query window.
+// Events of the window before are only read for velocity, and suspicious
+// events are left out. Counting unique users keeps only the latest event of
+// each type per actor and window, and counts actors as interactions.
+// area is a condition on the live articles of the events, with its
+// arguments, so only events of articles in the area are aggregated.
+func liveTrendingSQL(query models.TrendingQuery, now time.Time, area string, areaArgs []interface{}) (string, []interface{}) {
+	since := now.Add(-query.Window)
+	previousSince := since
+	if query.Algorithm == models.TrendingVelocity {
+		previousSince = since.Add(-query.Window)
+	}
+
+	countedSQL := `SELECT * FROM actor_events`
+	interactionSQL := `COUNT(*)`
+	if query.UniqueUsers {
+		countedSQL = `SELECT DISTINCT ON (article_id, actor, event_type, in_window) *
+            FROM actor_events
+            ORDER BY article_id, actor, event_type, in_window, timestamp DESC`
+		interactionSQL = `COUNT(DISTINCT actor)`
+	}
+
+	weightSQL, weightArgs := eventWeightSQL(query.Weights)
+	sql := `actor_events AS (
+            SELECT ue.article_id, ue.event_type, ue.timestamp,
+                   ` + actorSQL + ` AS actor,
+                   ue.timestamp > ? AS in_window
+            FROM user_events ue
+            JOIN articles a ON a.id = ue.article_id
+            WHERE ue.timestamp > ?
+              AND NOT ue.suspicious
+              AND a.deleted_at IS NULL
+              AND ` + area + `
+        ),
+        counted_events AS (
+            ` + countedSQL + `
+        ),
+        weighted_events AS (
+            SELECT ue.article_id, ue.timestamp, ue.actor,
+                   ` + weightSQL + ` AS weight,
+                   EXTRACT(EPOCH FROM (?::timestamptz - ue.timestamp)) / 3600.0 AS age_hours
+            FROM counted_events ue
+        ),
+        event_stats AS (
+            SELECT 
+                article_id,
+                ` + interactionSQL + ` FILTER (WHERE timestamp > ?) AS interaction_count,
+                COALESCE(SUM(weight) FILTER (WHERE timestamp > ?), 0) AS weighted_score,
+                COALESCE(SUM(weight) FILTER (WHERE timestamp <= ?), 0) AS previous_score,
+                COALESCE(SUM(weight * power(0.5, age_hours / ?)) FILTER (WHERE timestamp > ?), 0) AS decayed_score,
+                COALESCE(MIN(age_hours) FILTER (WHERE timestamp > ?), 0) AS hours_since_last
+            FROM weighted_events
+            GROUP BY article_id
+        ),
+        scored AS (
+            SELECT ts.article_id, ts.interaction_count,
+                   ` + trendingScoreSQL(query.Algorithm) + ` AS trending_score
+            FROM event_stats ts
+        )`
+
+	args := []interface{}{since, previousSince}
+	args = append(args, areaArgs...)
+	args = append(args, weightArgs...)
+	args = append(args, now,
+		since, since, since, query.HalfLife.Hours(), since, since,
+		query.MinInteractions)
+	return sql, args
+}
+
+// eventActor mirrors actorSQL
+func eventActor(event models.UserEvent) string {
+	switch {
+	case event.UserID != "":
+		return event.UserID
+	case event.DeviceID != "":
+		return event.DeviceID
+	default:
+		return fmt.Sprintf("event:%d", event.ID)
+	}
+}
+
+// trendingRow is an article as scanned from a trending query
+type trendingRow struct {
+	models.Article
+	TrendingScore    float64
+	InteractionCount int64
+}
+
+func toTrendingArticles(rows []trendingRow) []models.TrendingArticle {
+	results := make([]models.TrendingArticle, len(rows))
+	for i, row := range rows {
+		results[i] = models.TrendingArticle{
+			Article:          row.Article,
+			TrendingScore:    row.TrendingScore,
+			InteractionCount: row.InteractionCount,
+		}
+	}
+	return results
+}
+
+// trendingScoreSQL computes trendingScore over the columns of the
+// event_stats CTE. Its parameter is the minimum number of interactions.
+func trendingScoreSQL(algorithm string) string {
+	var score string
+	switch algorithm {
+	case models.TrendingDecay:
+		score = "ts.decayed_score"
+	case models.TrendingVelocity:
+		score = "ts.weighted_score - ts.previous_score"
+	default:
+		score = "(ts.weighted_score * 100) / (1 + ts.hours_since_last)"
+	}
+	return "CASE WHEN ts.interaction_count > 0 AND ts.interaction_count >= ? THEN " + score + " ELSE 0 END"
+}
+
+// eventWeightSQL is a CASE expression giving each event its weight, with
+// the types in a fixed order so the statement text is stable
+func eventWeightSQL(weights map[string]float64) (string, []interface{}) {
+	types := make([]string, 0, len(weights))
+	for eventType := range weights {
+		types = append(types, eventType)
+	}
+	sort.Strings(types)
+
+	var sql strings.Builder
+	args := make([]interface{}, 0, 2*len(types))
+	sql.WriteString("CASE ue.event_type")
+	for _, eventType := range types {
+		sql.WriteString(" WHEN ? THEN ?::float8")
+		args = append(args, eventType, weights[eventType])
+	}
+	sql.WriteString(" ELSE 0.0 END")
+	return sql.String(), args
+}
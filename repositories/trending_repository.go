@@ -0,0 +1,96 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"inshorts-news-api/models"
+)
+
+type TrendingRepository struct {
+	db *gorm.DB
+}
+
+func NewTrendingRepository(db *gorm.DB) *TrendingRepository {
+	return &TrendingRepository{db: db}
+}
+
+// ReplaceScores swaps the scores of a cell for a new set. A cell left
+// without scores is recorded as empty, so lookups know it was refreshed.
+func (r *TrendingRepository) ReplaceScores(ctx context.Context, cell string, windowHours int, algorithm string, scores []models.TrendingScore, refreshedAt time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("cell = ? AND window_hours = ? AND algorithm = ?", cell, windowHours, algorithm).
+			Delete(&models.TrendingScore{}).Error; err != nil {
+			return err
+		}
+
+		// An article moved to another cell still has a row under its old one
+		if len(scores) > 0 {
+			if err := tx.Clauses(clause.OnConflict{UpdateAll: true}).
+				CreateInBatches(scores, 500).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Save(&models.TrendingCell{
+			Cell:        cell,
+			WindowHours: windowHours,
+			Algorithm:   algorithm,
+			RefreshedAt: refreshedAt,
+			Empty:       len(scores) == 0,
+		}).Error
+	})
+}
+
+// GetCells returns the refresh records of cells. Cells that were never
+// refreshed are missing from the map.
+func (r *TrendingRepository) GetCells(ctx context.Context, cells []string, windowHours int, algorithm string) (map[string]models.TrendingCell, error) {
+	found := make(map[string]models.TrendingCell, len(cells))
+	if len(cells) == 0 {
+		return found, nil
+	}
+
+	var rows []models.TrendingCell
+	err := r.db.WithContext(ctx).
+		Where("cell IN ? AND window_hours = ? AND algorithm = ?", cells, windowHours, algorithm).
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		found[row.Cell] = row
+	}
+	return found, nil
+}
+
+// ListCellsRefreshedBefore returns the cells with scores refreshed before
+// a time. Empty cells are left out: they have no events to age out.
+func (r *TrendingRepository) ListCellsRefreshedBefore(ctx context.Context, before time.Time) ([]models.TrendingCell, error) {
+	var cells []models.TrendingCell
+	err := r.db.WithContext(ctx).Where("refreshed_at < ? AND NOT empty", before).
+		Order("refreshed_at").
+		Find(&cells).Error
+	return cells, err
+}
+
+// GetTrending ranks the articles near the query point by their
+// materialized score, like GetTrendingByLocation does with live events
+func (r *TrendingRepository) GetTrending(ctx context.Context, query models.TrendingQuery) ([]models.TrendingArticle, error) {
+	args := append(nearbyArticlesArgs(query), int(query.Window.Hours()), query.Algorithm, query.Limit)
+
+	var rows []trendingRow
+	err := r.db.WithContext(ctx).Raw(`WITH `+nearbyArticlesSQL+`,
+        scored AS (
+            SELECT article_id, score AS trending_score, interaction_count
+            FROM trending_scores
+            WHERE window_hours = ? AND algorithm = ?
+        )`+trendingSelectSQL, args...).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	return toTrendingArticles(rows), nil
+}
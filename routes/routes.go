@@ -1,13 +1,16 @@
 package routes
 
 import (
+	"expvar"
+	"time"
+
 	"github.com/gin-gonic/gin"
 
 	"inshorts-news-api/handlers"
 	"inshorts-news-api/middleware"
 )
 
-func SetupRoutes(r *gin.Engine, handler *handlers.ArticleHandler) {
+func SetupRoutes(r *gin.Engine, handler *handlers.ArticleHandler, eventHandler *handlers.EventHandler, adminHandler *handlers.AdminHandler, adminAuth gin.HandlerFunc) {
 	r.Use(middleware.ErrorHandler())
 
 	// Health check
@@ -15,19 +18,44 @@ func SetupRoutes(r *gin.Engine, handler *handlers.ArticleHandler) {
 		c.JSON(200, gin.H{"status": "ok"})
 	})
 
+	// Runtime metrics such as the event buffer depth, in expvar format
+	r.GET("/debug/vars", adminAuth, gin.WrapH(expvar.Handler()))
+
 	v1 := r.Group("/api/v1")
 	{
-		news := v1.Group("/news")
+		// Query and listing endpoints may wait on the LLM for summaries
+		news := v1.Group("/news", middleware.Timeout(30*time.Second))
 		{
+			news.GET("", handler.ListArticles)
 			news.GET("/query", handler.QueryNews)
 			news.GET("/category", handler.GetByCategory)
 			news.GET("/source", handler.GetBySource)
 			news.GET("/score", handler.GetByScore)
 			news.GET("/search", handler.Search)
+			news.GET("/semantic", handler.SemanticSearch)
 			news.GET("/nearby", handler.GetNearby)
+			news.GET("/within", handler.GetWithin)
+			news.POST("/within", handler.GetWithin)
 			news.GET("/trending", handler.GetTrending)
+			news.GET("/story/:id", handler.GetStory)
 		}
 
-		v1.POST("/events", handler.RecordEvent)
+		v1.GET("/events/types", eventHandler.ListEventTypes)
+		v1.POST("/events", middleware.Timeout(5*time.Second), eventHandler.RecordEvent)
+		v1.POST("/events/batch", middleware.Timeout(10*time.Second), eventHandler.RecordEventBatch)
+
+		admin := v1.Group("/admin/articles", adminAuth, middleware.Timeout(10*time.Second))
+		{
+			admin.POST("", adminHandler.CreateArticle)
+			admin.GET("/:id", adminHandler.GetArticle)
+			admin.PATCH("/:id", adminHandler.UpdateArticle)
+			admin.DELETE("/:id", adminHandler.DeleteArticle)
+			admin.POST("/:id/restore", adminHandler.RestoreArticle)
+		}
+
+		v1.GET("/admin/feeds", adminAuth, middleware.Timeout(10*time.Second), adminHandler.ListFeeds)
+
+		// Erases a user's events on a data deletion request
+		v1.DELETE("/admin/users/:id/events", adminAuth, middleware.Timeout(30*time.Second), eventHandler.DeleteUserEvents)
 	}
 }
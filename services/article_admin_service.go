@@ -0,0 +1,270 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"inshorts-news-api/clustering"
+	"inshorts-news-api/models"
+	"inshorts-news-api/repositories"
+	"inshorts-news-api/utils"
+)
+
+// ValidationError is returned when a request describes an invalid article
+// or query
+type ValidationError struct {
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+// categoryPattern allows the category styles found in the data, such as
+// "technology", "IPL_2025" and "Russia-Ukraine_Conflict"
+var categoryPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]{0,49}$`)
+
+// ArticleAdminService creates, edits, deletes and restores articles on
+// behalf of an admin, recording each change in the audit log
+type ArticleAdminService struct {
+	repo       repositories.ArticleStore
+	clusterer  *clustering.Clusterer
+	embeddings *EmbeddingService
+}
+
+func NewArticleAdminService(repo repositories.ArticleStore, clusterer *clustering.Clusterer) *ArticleAdminService {
+	return &ArticleAdminService{repo: repo, clusterer: clusterer}
+}
+
+// SetEmbeddings makes created and edited articles searchable by meaning
+// as soon as they are saved
+func (s *ArticleAdminService) SetEmbeddings(embeddings *EmbeddingService) {
+	s.embeddings = embeddings
+}
+
+func (s *ArticleAdminService) GetArticle(ctx context.Context, id string) (*models.Article, error) {
+	return s.repo.GetByID(ctx, id, true)
+}
+
+func (s *ArticleAdminService) CreateArticle(ctx context.Context, actor string, input models.ArticleInput) (*models.Article, error) {
+	article := &models.Article{
+		ID:              utils.NewID(),
+		PublicationDate: time.Now(),
+	}
+	input.ApplyTo(article)
+
+	if err := validateArticle(article); err != nil {
+		return nil, err
+	}
+
+	if s.clusterer != nil {
+		single := []models.Article{*article}
+		if err := s.clusterer.Assign(ctx, single); err != nil {
+			return nil, err
+		}
+		article.StoryID = single[0].StoryID
+	}
+
+	err := s.repo.Transaction(ctx, func(store repositories.ArticleStore) error {
+		if err := store.Create(ctx, article); err != nil {
+			return err
+		}
+		return store.CreateAuditLog(ctx, newAuditLog(article.ID, actor, models.AuditActionCreate, article))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.embed(ctx, *article)
+	return article, nil
+}
+
+func (s *ArticleAdminService) UpdateArticle(ctx context.Context, actor, id string, input models.ArticleInput) (*models.Article, error) {
+	var updated *models.Article
+
+	err := s.repo.Transaction(ctx, func(store repositories.ArticleStore) error {
+		before, err := store.GetByID(ctx, id, false)
+		if err != nil {
+			return err
+		}
+
+		after := *before
+		input.ApplyTo(&after)
+		if err := validateArticle(&after); err != nil {
+			return err
+		}
+
+		// New wording may no longer match the story the article was in
+		if s.clusterer != nil && (after.Title != before.Title || after.Description != before.Description) {
+			single := []models.Article{after}
+			if err := s.clusterer.Assign(ctx, single); err != nil {
+				return err
+			}
+			after.StoryID = single[0].StoryID
+		}
+
+		changes := diffArticles(*before, after)
+		if len(changes) == 0 {
+			updated = before
+			return nil
+		}
+
+		if err := store.Update(ctx, &after); err != nil {
+			return err
+		}
+		updated = &after
+
+		return store.CreateAuditLog(ctx, newAuditLog(id, actor, models.AuditActionUpdate, changes))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.embed(ctx, *updated)
+	return updated, nil
+}
+
+func (s *ArticleAdminService) DeleteArticle(ctx context.Context, actor, id string) error {
+	return s.repo.Transaction(ctx, func(store repositories.ArticleStore) error {
+		if err := store.Delete(ctx, id); err != nil {
+			return err
+		}
+		return store.CreateAuditLog(ctx, newAuditLog(id, actor, models.AuditActionDelete, nil))
+	})
+}
+
+func (s *ArticleAdminService) RestoreArticle(ctx context.Context, actor, id string) (*models.Article, error) {
+	var restored *models.Article
+
+	err := s.repo.Transaction(ctx, func(store repositories.ArticleStore) error {
+		if err := store.Restore(ctx, id); err != nil {
+			return err
+		}
+
+		article, err := store.GetByID(ctx, id, false)
+		if err != nil {
+			return err
+		}
+		restored = article
+
+		return store.CreateAuditLog(ctx, newAuditLog(id, actor, models.AuditActionRestore, nil))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return restored, nil
+}
+
+// embed refreshes the article's embedding, which is only recomputed when
+// its title or description changed. Failures are left to the backfill
+// rather than failing a change that is already saved.
+func (s *ArticleAdminService) embed(ctx context.Context, article models.Article) {
+	if s.embeddings == nil {
+		return
+	}
+	if _, err := s.embeddings.EmbedArticles(ctx, []models.Article{article}); err != nil && !errors.Is(err, ErrLLMUnavailable) {
+		log.Printf("Embedding article %s failed: %v", article.ID, err)
+	}
+}
+
+func validateArticle(article *models.Article) error {
+	article.Title = strings.TrimSpace(article.Title)
+	article.SourceName = strings.TrimSpace(article.SourceName)
+
+	if article.Title == "" {
+		return &ValidationError{"title is required"}
+	}
+	if article.SourceName == "" {
+		return &ValidationError{"source_name is required"}
+	}
+
+	u, err := url.Parse(article.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return &ValidationError{"url must be an absolute http(s) URL"}
+	}
+
+	if article.Latitude < -90 || article.Latitude > 90 {
+		return &ValidationError{"latitude must be between -90 and 90"}
+	}
+	if article.Longitude < -180 || article.Longitude > 180 {
+		return &ValidationError{"longitude must be between -180 and 180"}
+	}
+	if article.RelevanceScore < 0 || article.RelevanceScore > 1 {
+		return &ValidationError{"relevance_score must be between 0 and 1"}
+	}
+
+	if len(article.Category) == 0 {
+		return &ValidationError{"at least one category is required"}
+	}
+	seen := make(map[string]bool)
+	categories := make([]string, 0, len(article.Category))
+	for _, c := range article.Category {
+		c = strings.TrimSpace(c)
+		if !categoryPattern.MatchString(c) {
+			return &ValidationError{fmt.Sprintf("invalid category %q", c)}
+		}
+		if !seen[strings.ToLower(c)] {
+			seen[strings.ToLower(c)] = true
+			categories = append(categories, c)
+		}
+	}
+	article.Category = categories
+
+	return nil
+}
+
+type fieldChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// diffArticles compares the public JSON form of two articles field by field
+func diffArticles(before, after models.Article) map[string]fieldChange {
+	oldFields, newFields := jsonFields(before), jsonFields(after)
+
+	changes := make(map[string]fieldChange)
+	for field, newValue := range newFields {
+		if oldValue := oldFields[field]; !reflect.DeepEqual(oldValue, newValue) {
+			changes[field] = fieldChange{Old: oldValue, New: newValue}
+		}
+	}
+	return changes
+}
+
+func jsonFields(article models.Article) map[string]interface{} {
+	fields := make(map[string]interface{})
+	data, _ := json.Marshal(article)
+	_ = json.Unmarshal(data, &fields)
+	return fields
+}
+
+func newAuditLog(articleID, actor, action string, changes interface{}) *models.ArticleAuditLog {
+	data := []byte("{}")
+	if changes != nil {
+		if encoded, err := json.Marshal(changes); err == nil {
+			data = encoded
+		}
+	}
+
+	return &models.ArticleAuditLog{
+		ArticleID: articleID,
+		Actor:     actor,
+		Action:    action,
+		Changes:   string(data),
+	}
+}
+
+// IsValidationError reports whether err was caused by invalid input
+func IsValidationError(err error) bool {
+	var validationErr *ValidationError
+	return errors.As(err, &validationErr)
+}
@@ -1,47 +1,67 @@
 package services
 
 import (
+    "context"
     "fmt"
+    "log"
+    "time"
     
     "inshorts-news-api/models"
     "inshorts-news-api/repositories"
+    "inshorts-news-api/utils"
 )
 
 type ArticleService struct {
-    repo       *repositories.ArticleRepository
-    llmService *LLMService
+    repo             repositories.ArticleStore
+    summaryService   *SummaryService
+    embeddingService *EmbeddingService
+    trending         models.TrendingOptions
+    trendingCache    TrendingCache
 }
 
-func NewArticleService(repo *repositories.ArticleRepository, llmService *LLMService) *ArticleService {
+// TrendingCache serves trending queries from precomputed scores. Lookup
+// reports false when it cannot answer a query, and the live query is used.
+type TrendingCache interface {
+    Lookup(ctx context.Context, query models.TrendingQuery) ([]models.TrendingArticle, bool, error)
+}
+
+func NewArticleService(repo repositories.ArticleStore, summaryService *SummaryService, embeddingService *EmbeddingService, trending models.TrendingOptions) *ArticleService {
     return &ArticleService{
-        repo:       repo,
-        llmService: llmService,
+        repo:             repo,
+        summaryService:   summaryService,
+        embeddingService: embeddingService,
+        trending:         trending.WithDefaults(),
     }
 }
 
-func (s *ArticleService) GetArticlesByIntent(intent *models.QueryIntent, params map[string]interface{}) ([]models.ArticleResponse, error) {
+// SetTrendingCache makes GetTrending try cache before the live query
+func (s *ArticleService) SetTrendingCache(cache TrendingCache) {
+    s.trendingCache = cache
+}
+
+func (s *ArticleService) GetArticlesByIntent(ctx context.Context, intent *models.QueryIntent, params map[string]interface{}, page models.PageRequest) (*models.ArticlePage, error) {
     var articles []models.Article
+    var next *models.Cursor
     var err error
-    limit := 5
 
     switch intent.Intent {
     case "category":
         category := params["category"].(string)
-        articles, err = s.repo.GetByCategory(category, limit)
+        articles, next, err = s.repo.GetByCategory(ctx, category, page)
     case "source":
         source := params["source"].(string)
-        articles, err = s.repo.GetBySource(source, limit)
+        articles, next, err = s.repo.GetBySource(ctx, source, page)
     case "score":
         minScore := params["min_score"].(float64)
-        articles, err = s.repo.GetByScore(minScore, limit)
+        articles, next, err = s.repo.GetByScore(ctx, minScore, page)
     case "search":
         query := params["query"].(string)
-        articles, err = s.repo.SearchByText(query, limit)
+        articles, next, err = s.repo.SearchByText(ctx, query, page)
     case "nearby":
         lat := params["lat"].(float64)
         lon := params["lon"].(float64)
         radius := params["radius"].(float64)
-        articles, err = s.repo.GetNearby(lat, lon, radius, limit)
+        articles, next, err = s.repo.GetNearby(ctx, lat, lon, radius, page)
     default:
         return nil, fmt.Errorf("unknown intent: %s", intent.Intent)
     }
@@ -50,11 +70,57 @@ func (s *ArticleService) GetArticlesByIntent(intent *models.QueryIntent, params
         return nil, err
     }
 
-    return s.enrichArticles(articles)
+    return s.toPage(ctx, articles, next)
+}
+
+// QueryArticles returns one page of articles matching every filter at once
+func (s *ArticleService) QueryArticles(ctx context.Context, filter models.ArticleFilter, page models.PageRequest) (*models.ArticlePage, error) {
+    articles, next, err := s.repo.FindArticles(ctx, filter, page)
+    if err != nil {
+        return nil, err
+    }
+
+    return s.toPage(ctx, articles, next)
+}
+
+// GetWithin returns one page of articles inside area, newest first. With a
+// zoom level it also clusters every article in the area, not just the page,
+// so a map can draw the markers of the whole viewport.
+func (s *ArticleService) GetWithin(ctx context.Context, area models.GeoArea, zoom *int, page models.PageRequest) (*models.WithinPage, error) {
+    filter := models.ArticleFilter{Within: &area, Sort: models.SortByDate}
+    result, err := s.QueryArticles(ctx, filter, page)
+    if err != nil {
+        return nil, err
+    }
+
+    within := &models.WithinPage{
+        Articles:   result.Articles,
+        NextCursor: result.NextCursor,
+    }
+    if zoom != nil {
+        within.Clusters, err = s.repo.ClusterWithin(ctx, area, *zoom)
+        if err != nil {
+            return nil, err
+        }
+    }
+
+    return within, nil
+}
+
+func (s *ArticleService) toPage(ctx context.Context, articles []models.Article, next *models.Cursor) (*models.ArticlePage, error) {
+    responses, err := s.enrichArticles(ctx, articles)
+    if err != nil {
+        return nil, err
+    }
+
+    return &models.ArticlePage{
+        Articles:   responses,
+        NextCursor: utils.EncodeCursor(next),
+    }, nil
 }
 
-func (s *ArticleService) enrichArticles(articles []models.Article) ([]models.ArticleResponse, error) {
-    summaries, err := s.llmService.BatchGenerateSummaries(articles)
+func (s *ArticleService) enrichArticles(ctx context.Context, articles []models.Article) ([]models.ArticleResponse, error) {
+    summaries, err := s.summaryService.GetSummaries(ctx, articles)
     if err != nil {
         return nil, err
     }
@@ -72,32 +138,86 @@ func (s *ArticleService) enrichArticles(articles []models.Article) ([]models.Art
             LLMSummary:      summaries[article.ID],
             Latitude:        article.Latitude,
             Longitude:       article.Longitude,
+            TextRank:        article.TextRank,
+            Snippet:         article.Snippet,
+            Score:           article.Score,
+            DistanceKm:      article.Distance,
+            StoryID:         article.StoryID,
+            Duplicates:      article.Duplicates,
         }
     }
 
     return responses, nil
 }
 
-func (s *ArticleService) GetTrending(lat, lon, radius float64, limit, hoursBack int) ([]models.ArticleResponse, error) {
-    trendingArticles, err := s.repo.GetTrendingByLocation(lat, lon, radius, limit, hoursBack)
+// GetStory returns every article of a story, oldest first
+func (s *ArticleService) GetStory(ctx context.Context, storyID string) ([]models.ArticleResponse, error) {
+    articles, err := s.repo.GetByStory(ctx, storyID)
     if err != nil {
         return nil, err
     }
 
+    return s.enrichArticles(ctx, articles)
+}
+
+// GetTrending ranks the articles around a point by their interactions over
+// the last hoursBack hours. algorithm and uniqueUsers override the
+// configured options when set.
+func (s *ArticleService) GetTrending(ctx context.Context, lat, lon, radius float64, limit, hoursBack int, algorithm string, uniqueUsers *bool) (*models.TrendingPage, error) {
+    query := models.TrendingQuery{
+        TrendingOptions: s.trending,
+        Lat:             lat,
+        Lon:             lon,
+        RadiusKm:        radius,
+        Limit:           limit,
+        Window:          time.Duration(hoursBack) * time.Hour,
+    }
+    if algorithm != "" {
+        query.Algorithm = algorithm
+    }
+    if uniqueUsers != nil {
+        query.UniqueUsers = *uniqueUsers
+    }
+    if err := query.Validate(); err != nil {
+        return nil, &ValidationError{err.Error()}
+    }
+
+    var trendingArticles []models.TrendingArticle
+    materialized := false
+    if s.trendingCache != nil {
+        var err error
+        trendingArticles, materialized, err = s.trendingCache.Lookup(ctx, query)
+        if err != nil {
+            log.Printf("Falling back to live trending query: %v", err)
+            materialized = false
+        }
+    }
+    if !materialized {
+        var err error
+        trendingArticles, err = s.repo.GetTrendingByLocation(ctx, query)
+        if err != nil {
+            return nil, err
+        }
+    }
+
     articles := make([]models.Article, len(trendingArticles))
     for i, ta := range trendingArticles {
         articles[i] = ta.Article
     }
 
-    return s.enrichArticles(articles)
-}
-
-func (s *ArticleService) RecordUserEvent(articleID string, eventType string, lat, lon float64) error {
-    event := &models.UserEvent{
-        ArticleID: articleID,
-        EventType: eventType,
-        Latitude:  lat,
-        Longitude: lon,
+    responses, err := s.enrichArticles(ctx, articles)
+    if err != nil {
+        return nil, err
     }
-    return s.repo.CreateUserEvent(event)
+    for i := range trendingArticles {
+        responses[i].TrendingScore = &trendingArticles[i].TrendingScore
+        responses[i].InteractionCount = &trendingArticles[i].InteractionCount
+    }
+
+    return &models.TrendingPage{
+        Algorithm:    query.Algorithm,
+        UniqueUsers:  query.UniqueUsers,
+        Materialized: materialized,
+        Articles:     responses,
+    }, nil
 }
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"log"
+
+	"inshorts-news-api/models"
+	"inshorts-news-api/repositories"
+	"inshorts-news-api/vectorindex"
+)
+
+// indexLoadBatchSize is how many stored embeddings LoadIndex reads at once
+const indexLoadBatchSize = 1000
+
+// EmbeddingService keeps article embeddings in the article_embeddings table
+// and mirrors them into an in-process vector index for semantic search.
+type EmbeddingService struct {
+	repo       repositories.EmbeddingStore
+	llmService *LLMService
+	index      *vectorindex.Index
+}
+
+func NewEmbeddingService(repo repositories.EmbeddingStore, llmService *LLMService, index *vectorindex.Index) *EmbeddingService {
+	return &EmbeddingService{
+		repo:       repo,
+		llmService: llmService,
+		index:      index,
+	}
+}
+
+// LoadIndex adds every stored embedding of the current model to the index
+// and returns how many were loaded
+func (s *EmbeddingService) LoadIndex(ctx context.Context) (int, error) {
+	if !s.llmService.Enabled() {
+		return 0, nil
+	}
+
+	loaded := 0
+	afterID := ""
+	for {
+		embeddings, err := s.repo.ListAfterArticleID(ctx, s.llmService.EmbeddingModel(), afterID, indexLoadBatchSize)
+		if err != nil {
+			return loaded, err
+		}
+		if len(embeddings) == 0 {
+			return loaded, nil
+		}
+		afterID = embeddings[len(embeddings)-1].ArticleID
+
+		for _, embedding := range embeddings {
+			if err := s.index.Add(embedding.ArticleID, embedding.Vector); err != nil {
+				log.Printf("Skipping embedding of article %s: %v", embedding.ArticleID, err)
+				continue
+			}
+			loaded++
+		}
+	}
+}
+
+// EmbedArticles makes sure each article has an embedding for its current
+// content, calling the model only for articles that are new or changed. It
+// returns how many embeddings were generated.
+func (s *EmbeddingService) EmbedArticles(ctx context.Context, articles []models.Article) (int, error) {
+	if !s.llmService.Enabled() {
+		return 0, ErrLLMUnavailable
+	}
+	if len(articles) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]string, len(articles))
+	hashes := make(map[string]string, len(articles))
+	for i, article := range articles {
+		ids[i] = article.ID
+		hashes[article.ID] = contentHash(article)
+	}
+
+	model := s.llmService.EmbeddingModel()
+	stored, err := s.repo.GetByArticleIDs(ctx, ids, model)
+	if err != nil {
+		return 0, err
+	}
+
+	fresh := make(map[string]bool, len(stored))
+	for _, embedding := range stored {
+		if embedding.ContentHash == hashes[embedding.ArticleID] {
+			fresh[embedding.ArticleID] = true
+		}
+	}
+
+	var missing []models.Article
+	var texts []string
+	for _, article := range articles {
+		if !fresh[article.ID] {
+			missing = append(missing, article)
+			texts = append(texts, embeddingText(article))
+		}
+	}
+	if len(missing) == 0 {
+		return 0, nil
+	}
+
+	vectors, err := s.llmService.Embed(ctx, texts)
+	if err != nil {
+		return 0, err
+	}
+
+	rows := make([]models.ArticleEmbedding, len(missing))
+	for i, article := range missing {
+		rows[i] = models.ArticleEmbedding{
+			ArticleID:   article.ID,
+			Model:       model,
+			ContentHash: hashes[article.ID],
+			Vector:      vectors[i],
+		}
+	}
+
+	// As with summaries, keep what was paid for even if ctx is cancelled
+	if err := s.repo.Upsert(context.WithoutCancel(ctx), rows); err != nil {
+		return 0, err
+	}
+
+	for _, row := range rows {
+		if err := s.index.Add(row.ArticleID, row.Vector); err != nil {
+			log.Printf("Failed to index embedding of article %s: %v", row.ArticleID, err)
+		}
+	}
+
+	return len(rows), nil
+}
+
+// Search returns the k articles closest in meaning to query, best first
+func (s *EmbeddingService) Search(ctx context.Context, query string, k int) ([]vectorindex.Match, error) {
+	if !s.llmService.Enabled() {
+		return nil, ErrLLMUnavailable
+	}
+
+	vectors, err := s.llmService.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.index.Search(vectors[0], k), nil
+}
+
+// Backfill embeds every stored article that has no embedding for its
+// current content, batchSize articles at a time
+func (s *EmbeddingService) Backfill(ctx context.Context, store repositories.ArticleStore, batchSize int) (int, error) {
+	generated := 0
+	afterID := ""
+	for {
+		articles, err := store.ListAfterID(ctx, afterID, batchSize)
+		if err != nil {
+			return generated, err
+		}
+		if len(articles) == 0 {
+			return generated, nil
+		}
+		afterID = articles[len(articles)-1].ID
+
+		n, err := s.EmbedArticles(ctx, articles)
+		generated += n
+		if err != nil {
+			return generated, err
+		}
+		if n > 0 {
+			log.Printf("Backfilled %d embeddings (up to article %s)", generated, afterID)
+		}
+	}
+}
+
+func embeddingText(article models.Article) string {
+	return article.Title + "\n" + article.Description
+}
@@ -0,0 +1,276 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"inshorts-news-api/models"
+	"inshorts-news-api/repositories"
+)
+
+// MaxEventBatchSize bounds the events accepted by RecordUserEvents
+const MaxEventBatchSize = 500
+
+// maxIdempotencyKeyLength matches the size of the idempotency_key column
+const maxIdempotencyKeyLength = 128
+
+// maxIdentifierLength matches the size of the user, session and device ID
+// columns
+const maxIdentifierLength = 64
+
+// EventService validates and stores user events. Events must be of a
+// registered type and about an article that exists and is not deleted.
+type EventService struct {
+	repo      repositories.ArticleStore
+	types     models.EventTypes
+	options   models.EventOptions
+	publisher EventPublisher
+	filter    EventFilter
+	now       func() time.Time
+}
+
+// EventFilter screens valid events for abuse. Check returns false with a
+// reason to discard an event, and may mark it suspicious instead.
+type EventFilter interface {
+	Check(client models.EventClient, event *models.UserEvent) (string, bool)
+}
+
+// EventPublisher queues events to be stored in the background. Publish
+// reports which events it queued, leaving out those whose idempotency key
+// is already queued, and queues all of the others or none.
+type EventPublisher interface {
+	Publish(events ...models.UserEvent) ([]bool, error)
+}
+
+func NewEventService(repo repositories.ArticleStore, options models.EventOptions) *EventService {
+	options = options.WithDefaults()
+	return &EventService{
+		repo:    repo,
+		types:   models.NewEventTypes(options.ExtraTypes...),
+		options: options,
+		now:     time.Now,
+	}
+}
+
+// SetPublisher makes valid events go to publisher instead of being stored
+// on the request path. Batches then report queued events as accepted, and
+// events whose idempotency key is stored or queued as duplicates.
+func (s *EventService) SetPublisher(publisher EventPublisher) {
+	s.publisher = publisher
+}
+
+// SetFilter makes every valid event pass through filter before it is
+// stored
+func (s *EventService) SetFilter(filter EventFilter) {
+	s.filter = filter
+}
+
+// EventTypes lists the accepted event types
+func (s *EventService) EventTypes() []string {
+	return s.types.Names()
+}
+
+// RecordUserEvent stores one event from client, timestamped by the server.
+// It returns repositories.ErrArticleNotFound when the article does not
+// exist, and the filter's reason when the event was discarded.
+func (s *EventService) RecordUserEvent(ctx context.Context, client models.EventClient, event models.UserEvent) (string, error) {
+	event.Timestamp = time.Time{}
+	if err := s.prepare(&event, s.now()); err != nil {
+		return "", err
+	}
+
+	if _, err := s.repo.GetByID(ctx, event.ArticleID, false); err != nil {
+		return "", err
+	}
+	if s.filter != nil {
+		if reason, keep := s.filter.Check(client, &event); !keep {
+			return reason, nil
+		}
+	}
+	return "", s.store(ctx, event)
+}
+
+func (s *EventService) store(ctx context.Context, event models.UserEvent) error {
+	if s.publisher != nil {
+		_, err := s.publisher.Publish(event)
+		return err
+	}
+	return s.repo.CreateUserEvent(ctx, &event)
+}
+
+// RecordUserEvents stores a batch of events from client and returns the
+// outcome of each. Invalid events are rejected without failing the others,
+// and events without a timestamp are timestamped now.
+func (s *EventService) RecordUserEvents(ctx context.Context, client models.EventClient, events []models.UserEvent) ([]models.EventResult, error) {
+	if len(events) == 0 {
+		return nil, &ValidationError{"events must not be empty"}
+	}
+	if len(events) > MaxEventBatchSize {
+		return nil, &ValidationError{fmt.Sprintf("at most %d events are accepted per batch", MaxEventBatchSize)}
+	}
+
+	now := s.now()
+	results := make([]models.EventResult, len(events))
+	var ids []string
+	for i := range events {
+		results[i].Index = i
+		if err := s.prepare(&events[i], now); err != nil {
+			results[i].Status = models.EventRejected
+			results[i].Error = err.Error()
+			continue
+		}
+		ids = append(ids, events[i].ArticleID)
+	}
+
+	articles, err := s.repo.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	exists := make(map[string]bool, len(articles))
+	for _, article := range articles {
+		exists[article.ID] = true
+	}
+
+	var valid []models.UserEvent
+	var validIndexes []int
+	for i, event := range events {
+		if results[i].Status != "" {
+			continue
+		}
+		if !exists[event.ArticleID] {
+			results[i].Status = models.EventRejected
+			results[i].Error = repositories.ErrArticleNotFound.Error()
+			continue
+		}
+		if s.filter != nil {
+			if reason, keep := s.filter.Check(client, &event); !keep {
+				results[i].Status = models.EventDiscarded
+				results[i].Error = reason
+				continue
+			}
+		}
+		valid = append(valid, event)
+		validIndexes = append(validIndexes, i)
+	}
+
+	if len(valid) > 0 {
+		var stored []bool
+		var err error
+		if s.publisher != nil {
+			stored, err = s.publish(ctx, valid)
+		} else {
+			stored, err = s.repo.CreateUserEvents(ctx, valid)
+		}
+		if err != nil {
+			return nil, err
+		}
+		for i, index := range validIndexes {
+			if stored[i] {
+				results[index].Status = models.EventAccepted
+			} else {
+				results[index].Status = models.EventDuplicate
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// publish queues the events whose idempotency key is not stored yet, and
+// reports which it queued. A duplicate of an event that finishes storing
+// between the two checks is reported as accepted, but still stored once.
+func (s *EventService) publish(ctx context.Context, events []models.UserEvent) ([]bool, error) {
+	var keys []string
+	for _, event := range events {
+		if event.IdempotencyKey != nil {
+			keys = append(keys, *event.IdempotencyKey)
+		}
+	}
+	existing, err := s.repo.StoredIdempotencyKeys(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	stored := make(map[string]bool, len(existing))
+	for _, key := range existing {
+		stored[key] = true
+	}
+
+	queued := make([]bool, len(events))
+	var fresh []models.UserEvent
+	var freshIndexes []int
+	for i, event := range events {
+		if event.IdempotencyKey == nil || !stored[*event.IdempotencyKey] {
+			fresh = append(fresh, event)
+			freshIndexes = append(freshIndexes, i)
+		}
+	}
+	if len(fresh) == 0 {
+		return queued, nil
+	}
+
+	published, err := s.publisher.Publish(fresh...)
+	if err != nil {
+		return nil, err
+	}
+	for i, index := range freshIndexes {
+		queued[index] = published[i]
+	}
+	return queued, nil
+}
+
+// DeleteUserEvents erases every stored event of a user, for data deletion
+// requests. Events of the user still buffered by the publisher are stored
+// after it returns, so requests should be served once the buffer has been
+// flushed, which takes about a flush interval.
+func (s *EventService) DeleteUserEvents(ctx context.Context, userID string) (int64, error) {
+	if userID == "" {
+		return 0, &ValidationError{"user_id is required"}
+	}
+	return s.repo.DeleteUserEvents(ctx, userID)
+}
+
+// prepare validates an event and settles its timestamp: now when the
+// client gave none, and now as well when the client clock is ahead by less
+// than the allowed skew
+func (s *EventService) prepare(event *models.UserEvent, now time.Time) error {
+	if event.ArticleID == "" {
+		return &ValidationError{"article_id is required"}
+	}
+	if event.EventType == "" {
+		return &ValidationError{"event_type is required"}
+	}
+	if !s.types.Has(event.EventType) {
+		return &ValidationError{fmt.Sprintf("unknown event_type %q", event.EventType)}
+	}
+	if event.Latitude < -90 || event.Latitude > 90 {
+		return &ValidationError{"latitude must be between -90 and 90"}
+	}
+	if event.Longitude < -180 || event.Longitude > 180 {
+		return &ValidationError{"longitude must be between -180 and 180"}
+	}
+	for _, id := range []struct{ name, value string }{
+		{"user_id", event.UserID},
+		{"session_id", event.SessionID},
+		{"device_id", event.DeviceID},
+	} {
+		if len(id.value) > maxIdentifierLength {
+			return &ValidationError{fmt.Sprintf("%s must be at most %d characters", id.name, maxIdentifierLength)}
+		}
+	}
+	if event.IdempotencyKey != nil && (*event.IdempotencyKey == "" || len(*event.IdempotencyKey) > maxIdempotencyKeyLength) {
+		return &ValidationError{fmt.Sprintf("idempotency_key must be 1 to %d characters", maxIdempotencyKeyLength)}
+	}
+
+	switch {
+	case event.Timestamp.IsZero():
+		event.Timestamp = now
+	case event.Timestamp.After(now.Add(s.options.MaxClockSkew)):
+		return &ValidationError{fmt.Sprintf("timestamp is more than %s in the future", s.options.MaxClockSkew)}
+	case event.Timestamp.Before(now.Add(-s.options.MaxAge)):
+		return &ValidationError{fmt.Sprintf("timestamp is more than %s old", s.options.MaxAge)}
+	case event.Timestamp.After(now):
+		event.Timestamp = now
+	}
+	return nil
+}
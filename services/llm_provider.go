@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"inshorts-news-api/config"
+)
+
+const (
+	RoleSystem = "system"
+	RoleUser   = "user"
+)
+
+// What a chat request asks for
+const (
+	TaskSummary       = "summary"
+	TaskQueryAnalysis = "query_analysis"
+)
+
+var (
+	ErrEmptyCompletion = errors.New("llm returned no completion")
+	// ErrUnsupportedTask is returned by providers that cannot answer a task
+	ErrUnsupportedTask = errors.New("llm provider does not support this task")
+)
+
+type ChatMessage struct {
+	Role    string
+	Content string
+}
+
+// ChatRequest is a prompt for the chat model. Task and Text say what the
+// prompt asks for and about which text, so providers that do not run a
+// model can answer in kind.
+type ChatRequest struct {
+	Messages    []ChatMessage
+	Temperature float32
+	MaxTokens   int
+	Task        string
+	Text        string
+}
+
+// LLMProvider is the model backend behind LLMService
+type LLMProvider interface {
+	// Model names the chat model, so output can be attributed to it
+	Model() string
+	ChatCompletion(ctx context.Context, req ChatRequest) (string, error)
+	// EmbeddingModel names the model behind CreateEmbeddings, since vectors
+	// from different models cannot be compared
+	EmbeddingModel() string
+	CreateEmbeddings(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// NewLLMProvider builds the provider selected by LLM_PROVIDER. A nil provider
+// means no model is configured and LLMService uses its keyword fallbacks.
+func NewLLMProvider(cfg *config.Config) (LLMProvider, error) {
+	switch cfg.LLMProvider {
+	case "", "none":
+		return nil, nil
+	case "openai":
+		if cfg.OpenAIKey == "" {
+			return nil, nil
+		}
+		return NewOpenAIProvider(cfg.OpenAIKey, "", cfg.LLMModel, cfg.LLMEmbeddingModel), nil
+	case "openai_compatible":
+		if cfg.LLMBaseURL == "" {
+			return nil, errors.New("LLM_BASE_URL is required for the openai_compatible provider")
+		}
+		return NewOpenAIProvider(cfg.OpenAIKey, cfg.LLMBaseURL, cfg.LLMModel, cfg.LLMEmbeddingModel), nil
+	case "offline":
+		return NewOfflineProvider(), nil
+	default:
+		return nil, fmt.Errorf("unknown LLM provider: %s", cfg.LLMProvider)
+	}
+}
@@ -3,29 +3,52 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"strings"
-
-	"github.com/sashabaranov/go-openai"
+	"sync"
+	"time"
 
 	"inshorts-news-api/models"
 )
 
+var ErrLLMUnavailable = errors.New("no llm provider configured")
+
+// SummaryOptions bounds how BatchGenerateSummaries spends time on the model
+type SummaryOptions struct {
+	Concurrency int
+	Timeout     time.Duration // per article
+	Budget      time.Duration // whole batch
+}
+
 type LLMService struct {
-	client *openai.Client
+	provider       LLMProvider
+	summaryOptions SummaryOptions
 }
 
-func NewLLMService(apiKey string) *LLMService {
-	if apiKey == "" {
-		return &LLMService{client: nil}
-	}
+func NewLLMService(provider LLMProvider, summaryOptions SummaryOptions) *LLMService {
 	return &LLMService{
-		client: openai.NewClient(apiKey),
+		provider:       provider,
+		summaryOptions: summaryOptions,
+	}
+}
+
+// Enabled reports whether a model is configured
+func (s *LLMService) Enabled() bool {
+	return s.provider != nil
+}
+
+// Model names the model producing summaries, or "" without a provider
+func (s *LLMService) Model() string {
+	if s.provider == nil {
+		return ""
 	}
+	return s.provider.Model()
 }
 
-func (s *LLMService) AnalyzeQuery(query string, userLocation string) (*models.QueryIntent, error) {
-	if s.client == nil {
+func (s *LLMService) AnalyzeQuery(ctx context.Context, query string, userLocation string) (*models.QueryIntent, error) {
+	if s.provider == nil {
 		// Fallback: simple keyword-based intent detection
 		return s.fallbackAnalyzeQuery(query), nil
 	}
@@ -34,6 +57,13 @@ func (s *LLMService) AnalyzeQuery(query string, userLocation string) (*models.Qu
 1. Intent: Choose ONE from [category, source, search, nearby, score]
 2. Entities: Key people, organizations, locations, events
 3. Concepts: Main topics or themes
+4. Filters: every constraint the query states, left empty when absent
+   - categories: news categories such as technology, business, sports, politics, world, national
+   - sources: publishers named in the query, e.g. Reuters, News18
+   - location: the place the news should be about or near
+   - date_from / date_to: YYYY-MM-DD, resolving relative dates against today (%s)
+   - min_score: 0-1, only when the query asks for important or highly relevant news
+   - keywords: remaining search terms, without the words used for the filters above
 
 Query: "%s"
 User Location Context: %s
@@ -42,40 +72,44 @@ Return JSON only in this exact format:
 {
   "intent": "<one of: category, source, search, nearby, score>",
   "entities": ["entity1", "entity2"],
-  "concepts": ["concept1", "concept2"]
-}`, query, userLocation)
-
-	resp, err := s.client.CreateChatCompletion(
-		context.Background(),
-		openai.ChatCompletionRequest{
-			Model: openai.GPT3Dot5Turbo,
-			Messages: []openai.ChatCompletionMessage{
+  "concepts": ["concept1", "concept2"],
+  "categories": [],
+  "sources": [],
+  "location": "",
+  "date_from": "",
+  "date_to": "",
+  "min_score": null,
+  "keywords": []
+}`, time.Now().Format("2006-01-02"), query, userLocation)
+
+	content, err := s.provider.ChatCompletion(
+		ctx,
+		ChatRequest{
+			Messages: []ChatMessage{
 				{
-					Role:    openai.ChatMessageRoleSystem,
+					Role:    RoleSystem,
 					Content: "You are a query analysis assistant. Return only valid JSON.",
 				},
 				{
-					Role:    openai.ChatMessageRoleUser,
+					Role:    RoleUser,
 					Content: prompt,
 				},
 			},
 			Temperature: 0.3,
+			Task:        TaskQueryAnalysis,
+			Text:        query,
 		},
 	)
 
 	if err != nil {
-		fmt.Printf("LLM Error: %v\n", err)
+		if !errors.Is(err, ErrUnsupportedTask) {
+			log.Printf("Query analysis failed, using keyword fallback: %v", err)
+		}
 		// Fallback to keyword analysis on error
 		return s.fallbackAnalyzeQuery(query), nil
 	}
 
-	// Check if response has choices
-	if len(resp.Choices) == 0 {
-		return s.fallbackAnalyzeQuery(query), nil
-	}
-
-	var intent models.QueryIntent
-	content := resp.Choices[0].Message.Content
+	var intent analyzedIntent
 
 	// Clean up response
 	content = strings.TrimSpace(content)
@@ -89,7 +123,7 @@ Return JSON only in this exact format:
 		return s.fallbackAnalyzeQuery(query), nil
 	}
 
-	return &intent, nil
+	return intent.toQueryIntent(), nil
 }
 
 func (s *LLMService) fallbackAnalyzeQuery(query string) *models.QueryIntent {
@@ -127,16 +161,19 @@ func (s *LLMService) fallbackAnalyzeQuery(query string) *models.QueryIntent {
 		}
 	}
 
+	extractSlots(query, intent, time.Now())
+	if intent.Intent == "search" && len(intent.Sources) > 0 {
+		intent.Intent = "source"
+	}
+
 	return intent
 }
 
-func (s *LLMService) GenerateSummary(title, description string) (string, error) {
-	if s.client == nil {
-		// Fallback: return truncated description
-		if len(description) > 150 {
-			return description[:150] + "...", nil
-		}
-		return description, nil
+// GenerateSummary asks the model for a summary. Unlike AnalyzeQuery it does
+// not fall back on its own, so callers can tell model output from filler.
+func (s *LLMService) GenerateSummary(ctx context.Context, title, description string) (string, error) {
+	if s.provider == nil {
+		return "", ErrLLMUnavailable
 	}
 
 	prompt := fmt.Sprintf(`Summarize this news article in 2-3 sentences:
@@ -146,62 +183,159 @@ Description: %s
 
 Provide a concise, informative summary.`, title, description)
 
-	resp, err := s.client.CreateChatCompletion(
-		context.Background(),
-		openai.ChatCompletionRequest{
-			Model: openai.GPT3Dot5Turbo,
-			Messages: []openai.ChatCompletionMessage{
+	content, err := s.provider.ChatCompletion(
+		ctx,
+		ChatRequest{
+			Messages: []ChatMessage{
 				{
-					Role:    openai.ChatMessageRoleUser,
+					Role:    RoleUser,
 					Content: prompt,
 				},
 			},
 			Temperature: 0.5,
 			MaxTokens:   150,
+			Task:        TaskSummary,
+			Text:        summaryText(title, description),
 		},
 	)
-
 	if err != nil {
-		// Fallback on error
-		if len(description) > 150 {
-			return description[:150] + "...", nil
-		}
-		return description, nil
+		return "", err
 	}
 
-	// Check if response has choices
-	if len(resp.Choices) == 0 {
-		if len(description) > 150 {
-			return description[:150] + "...", nil
-		}
-		return description, nil
-	}
-
-	summary := strings.TrimSpace(resp.Choices[0].Message.Content)
+	summary := strings.TrimSpace(content)
 	if summary == "" {
-		if len(description) > 150 {
-			return description[:150] + "...", nil
-		}
-		return description, nil
+		return "", ErrEmptyCompletion
 	}
 
 	return summary, nil
 }
 
-func (s *LLMService) BatchGenerateSummaries(articles []models.Article) (map[string]string, error) {
+// summaryText is the text a summary is drawn from: the description, or the
+// title of articles without one
+func summaryText(title, description string) string {
+	if strings.TrimSpace(description) == "" {
+		return title
+	}
+	return description
+}
+
+// BatchGenerateSummaries summarises articles concurrently and returns the
+// summaries keyed by article ID. Articles the model failed on or that ran
+// out of time are left out; use FallbackSummary for those. An error is only
+// returned when ctx itself is cancelled, along with the partial results.
+func (s *LLMService) BatchGenerateSummaries(ctx context.Context, articles []models.Article) (map[string]string, error) {
 	summaries := make(map[string]string)
+	if s.provider == nil || len(articles) == 0 {
+		return summaries, nil
+	}
 
-	for _, article := range articles {
-		summary, err := s.GenerateSummary(article.Title, article.Description)
-		if err != nil {
-			// Use fallback on error
-			summary = article.Description
-			if len(summary) > 150 {
-				summary = summary[:150] + "..."
+	batchCtx := ctx
+	if s.summaryOptions.Budget > 0 {
+		var cancel context.CancelFunc
+		batchCtx, cancel = context.WithTimeout(ctx, s.summaryOptions.Budget)
+		defer cancel()
+	}
+
+	workers := s.summaryOptions.Concurrency
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(articles) {
+		workers = len(articles)
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	jobs := make(chan models.Article)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for article := range jobs {
+				summary, err := s.generateWithTimeout(batchCtx, article)
+				if err != nil {
+					continue
+				}
+				mu.Lock()
+				summaries[article.ID] = summary
+				mu.Unlock()
 			}
+		}()
+	}
+
+dispatch:
+	for _, article := range articles {
+		select {
+		case jobs <- article:
+		case <-batchCtx.Done():
+			break dispatch
 		}
-		summaries[article.ID] = summary
+	}
+	close(jobs)
+	wg.Wait()
+
+	// Running out of budget still leaves useful partial results, but a
+	// cancelled request has nobody left to read them
+	if err := ctx.Err(); err != nil {
+		return summaries, err
 	}
 
 	return summaries, nil
 }
+
+func (s *LLMService) generateWithTimeout(ctx context.Context, article models.Article) (string, error) {
+	if s.summaryOptions.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.summaryOptions.Timeout)
+		defer cancel()
+	}
+
+	return s.GenerateSummary(ctx, article.Title, article.Description)
+}
+
+// FallbackSummary is the truncated description shown when no model summary
+// is available
+func FallbackSummary(description string) string {
+	if len(description) > 150 {
+		return description[:150] + "..."
+	}
+	return description
+}
+
+// embeddingBatchSize keeps embedding requests well under provider limits
+const embeddingBatchSize = 100
+
+// EmbeddingModel names the model behind Embed, or "" without a provider
+func (s *LLMService) EmbeddingModel() string {
+	if s.provider == nil {
+		return ""
+	}
+	return s.provider.EmbeddingModel()
+}
+
+// Embed returns one embedding per text, in order
+func (s *LLMService) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if s.provider == nil {
+		return nil, ErrLLMUnavailable
+	}
+
+	embeddings := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += embeddingBatchSize {
+		end := start + embeddingBatchSize
+		if end > len(texts) {
+			end = len(texts)
+		}
+
+		batch, err := s.provider.CreateEmbeddings(ctx, texts[start:end])
+		if err != nil {
+			return nil, err
+		}
+		if len(batch) != end-start {
+			return nil, fmt.Errorf("expected %d embeddings, got %d", end-start, len(batch))
+		}
+		embeddings = append(embeddings, batch...)
+	}
+
+	return embeddings, nil
+}
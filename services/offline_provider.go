@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+const offlineEmbeddingDims = 256
+
+// offlineSummarySentences is how many leading sentences make a summary
+const offlineSummarySentences = 2
+
+// OfflineProvider is a deterministic stand-in for a real model. It never
+// leaves the process, so tests and local runs get repeatable output.
+// Summaries are extractive; other tasks fail with ErrUnsupportedTask, so
+// callers fall back as they would without a model.
+type OfflineProvider struct {
+	// Reply produces the completion for a request, in place of the
+	// built-in answers
+	Reply func(req ChatRequest) string
+}
+
+func NewOfflineProvider() *OfflineProvider {
+	return &OfflineProvider{}
+}
+
+func (p *OfflineProvider) Model() string {
+	return "offline"
+}
+
+func (p *OfflineProvider) EmbeddingModel() string {
+	return "offline-hash"
+}
+
+func (p *OfflineProvider) ChatCompletion(ctx context.Context, req ChatRequest) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	if p.Reply != nil {
+		return p.Reply(req), nil
+	}
+
+	if req.Task != TaskSummary {
+		return "", ErrUnsupportedTask
+	}
+	summary := leadingSentences(req.Text, offlineSummarySentences)
+	if summary == "" {
+		return "", ErrEmptyCompletion
+	}
+	return summary, nil
+}
+
+// leadingSentences returns the first n sentences of text, with whitespace
+// collapsed. A sentence ends at '.', '!' or '?' followed by a space.
+func leadingSentences(text string, n int) string {
+	words := strings.Fields(text)
+	for i, word := range words {
+		if strings.ContainsAny(word[len(word)-1:], ".!?") {
+			if n--; n == 0 {
+				return strings.Join(words[:i+1], " ")
+			}
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// CreateEmbeddings hashes words into a fixed number of buckets, so texts
+// sharing vocabulary end up close together
+func (p *OfflineProvider) CreateEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	embeddings := make([][]float32, len(texts))
+	for i, text := range texts {
+		embeddings[i] = hashEmbedding(text)
+	}
+	return embeddings, nil
+}
+
+func hashEmbedding(text string) []float32 {
+	vector := make([]float32, offlineEmbeddingDims)
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		h := fnv.New32a()
+		h.Write([]byte(word))
+		sum := h.Sum32()
+
+		// The top bit picks the sign so unrelated words tend to cancel out
+		if sum&(1<<31) != 0 {
+			vector[sum%offlineEmbeddingDims]--
+		} else {
+			vector[sum%offlineEmbeddingDims]++
+		}
+	}
+
+	var norm float64
+	for _, v := range vector {
+		norm += float64(v) * float64(v)
+	}
+	if norm > 0 {
+		norm = math.Sqrt(norm)
+		for i := range vector {
+			vector[i] = float32(float64(vector[i]) / norm)
+		}
+	}
+
+	return vector
+}
@@ -0,0 +1,29 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestOfflineProvider(t *testing.T) {
+	ctx := context.Background()
+	llm := NewLLMService(NewOfflineProvider(), SummaryOptions{})
+
+	summary, err := llm.GenerateSummary(ctx, "Metro opens", "The metro line opens today.  Trains run every   five minutes! Fares start at Rs 10. More lines follow.")
+	if err != nil || summary != "The metro line opens today. Trains run every five minutes!" {
+		t.Fatalf("expected the leading two sentences, got %q %v", summary, err)
+	}
+	if summary, err := llm.GenerateSummary(ctx, "Metro opens", ""); err != nil || summary != "Metro opens" {
+		t.Fatalf("expected the title without a description, got %q %v", summary, err)
+	}
+
+	// Query analysis is left to the keyword fallback rather than echoed
+	if _, err := NewOfflineProvider().ChatCompletion(ctx, ChatRequest{Task: TaskQueryAnalysis, Text: "tech news"}); !errors.Is(err, ErrUnsupportedTask) {
+		t.Fatalf("expected ErrUnsupportedTask, got %v", err)
+	}
+	intent, err := llm.AnalyzeQuery(ctx, "latest technology news", "")
+	if err != nil || intent.Intent != "category" {
+		t.Fatalf("expected the keyword analysis, got %+v %v", intent, err)
+	}
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// OpenAIProvider talks to the OpenAI API or to any server exposing the same
+// API (Ollama, vLLM, llama.cpp server) when a base URL is given.
+type OpenAIProvider struct {
+	client         *openai.Client
+	httpClient     *http.Client
+	apiKey         string
+	baseURL        string
+	model          string
+	embeddingModel string
+}
+
+func NewOpenAIProvider(apiKey, baseURL, model, embeddingModel string) *OpenAIProvider {
+	clientConfig := openai.DefaultConfig(apiKey)
+	if baseURL != "" {
+		clientConfig.BaseURL = strings.TrimSuffix(baseURL, "/")
+	}
+
+	return &OpenAIProvider{
+		client:         openai.NewClientWithConfig(clientConfig),
+		httpClient:     http.DefaultClient,
+		apiKey:         apiKey,
+		baseURL:        clientConfig.BaseURL,
+		model:          model,
+		embeddingModel: embeddingModel,
+	}
+}
+
+func (p *OpenAIProvider) Model() string {
+	return p.model
+}
+
+func (p *OpenAIProvider) EmbeddingModel() string {
+	return p.embeddingModel
+}
+
+func (p *OpenAIProvider) ChatCompletion(ctx context.Context, req ChatRequest) (string, error) {
+	messages := make([]openai.ChatCompletionMessage, len(req.Messages))
+	for i, m := range req.Messages {
+		messages[i] = openai.ChatCompletionMessage{Role: m.Role, Content: m.Content}
+	}
+
+	resp, err := p.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model:       p.model,
+		Messages:    messages,
+		Temperature: req.Temperature,
+		MaxTokens:   req.MaxTokens,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if len(resp.Choices) == 0 {
+		return "", ErrEmptyCompletion
+	}
+
+	return resp.Choices[0].Message.Content, nil
+}
+
+// CreateEmbeddings calls the embeddings endpoint directly: the client library
+// only accepts OpenAI's own model names, while local servers use their own.
+func (p *OpenAIProvider) CreateEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"model": p.embeddingModel,
+		"input": texts,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("embeddings request failed with status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Data []struct {
+			Embedding []float32 `json:"embedding"`
+			Index     int       `json:"index"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode embeddings: %w", err)
+	}
+
+	if len(result.Data) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(result.Data))
+	}
+
+	embeddings := make([][]float32, len(texts))
+	for _, d := range result.Data {
+		if d.Index < 0 || d.Index >= len(texts) {
+			return nil, fmt.Errorf("embedding index %d out of range", d.Index)
+		}
+		embeddings[d.Index] = d.Embedding
+	}
+
+	return embeddings, nil
+}
@@ -0,0 +1,224 @@
+package services
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"inshorts-news-api/models"
+)
+
+// analyzedIntent is the JSON the LLM returns. Dates arrive as plain strings
+// and are only kept when they parse.
+type analyzedIntent struct {
+	Intent     string   `json:"intent"`
+	Entities   []string `json:"entities"`
+	Concepts   []string `json:"concepts"`
+	Categories []string `json:"categories"`
+	Sources    []string `json:"sources"`
+	Location   string   `json:"location"`
+	DateFrom   string   `json:"date_from"`
+	DateTo     string   `json:"date_to"`
+	MinScore   *float64 `json:"min_score"`
+	Keywords   []string `json:"keywords"`
+}
+
+func (a analyzedIntent) toQueryIntent() *models.QueryIntent {
+	intent := &models.QueryIntent{
+		Intent:     a.Intent,
+		Entities:   a.Entities,
+		Concepts:   a.Concepts,
+		Categories: nonEmpty(a.Categories),
+		Sources:    nonEmpty(a.Sources),
+		Location:   strings.TrimSpace(a.Location),
+		Keywords:   nonEmpty(a.Keywords),
+		DateFrom:   parseIntentDate(a.DateFrom, false),
+		DateTo:     parseIntentDate(a.DateTo, true),
+	}
+
+	if a.MinScore != nil && *a.MinScore >= 0 && *a.MinScore <= 1 {
+		intent.MinScore = a.MinScore
+	}
+
+	return intent
+}
+
+// parseIntentDate accepts YYYY-MM-DD or RFC3339. A bare date used as the end
+// of a range covers that whole day.
+func parseIntentDate(value string, endOfDay bool) *time.Time {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t
+	}
+
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return &t
+}
+
+func nonEmpty(values []string) []string {
+	var kept []string
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			kept = append(kept, v)
+		}
+	}
+	return kept
+}
+
+// fallbackCategories maps query words to the category names used in the data
+var fallbackCategories = map[string]string{
+	"tech":          "technology",
+	"technology":    "technology",
+	"business":      "business",
+	"sports":        "sports",
+	"sport":         "sports",
+	"cricket":       "cricket",
+	"ipl":           "IPL",
+	"politics":      "politics",
+	"political":     "politics",
+	"entertainment": "entertainment",
+	"bollywood":     "bollywood",
+	"science":       "science",
+	"health":        "Health___Fitness",
+	"fitness":       "Health___Fitness",
+	"startup":       "startup",
+	"startups":      "startup",
+	"education":     "education",
+	"crime":         "crime",
+	"finance":       "FINANCE",
+	"world":         "world",
+	"national":      "national",
+	"travel":        "travel",
+}
+
+// fallbackStopWords never make useful search keywords
+var fallbackStopWords = map[string]bool{
+	"a": true, "about": true, "all": true, "an": true, "and": true, "any": true,
+	"are": true, "around": true, "articles": true, "at": true, "category": true,
+	"for": true, "from": true, "get": true, "give": true, "headlines": true,
+	"important": true, "in": true, "is": true, "latest": true, "me": true,
+	"near": true, "nearby": true, "news": true, "of": true, "on": true,
+	"or": true, "recent": true, "regarding": true, "relevant": true,
+	"show": true, "stories": true, "the": true, "to": true, "top": true,
+	"updates": true, "what": true, "whats": true, "with": true,
+}
+
+var (
+	lastPeriodPattern = regexp.MustCompile(`\b(?:last|past) (\d+) (hour|hours|day|days)\b`)
+	sourcePattern     = regexp.MustCompile(`\bfrom ((?:the )?[A-Z][\w.&'-]*(?: (?:of )?[A-Z][\w.&'-]*)*)`)
+	locationPattern   = regexp.MustCompile(`\b(?:in|near|around|at) ([A-Z][\w'-]*(?: [A-Z][\w'-]*)*)`)
+)
+
+// extractSlots fills the typed slots of intent from the query text with
+// simple patterns. It backs AnalyzeQuery when no LLM is available.
+func extractSlots(query string, intent *models.QueryIntent, now time.Time) {
+	lower := strings.ToLower(query)
+	consumed := make(map[string]bool)
+
+	for _, word := range tokenizeQuery(lower) {
+		if category, ok := fallbackCategories[word]; ok {
+			intent.Categories = appendUnique(intent.Categories, category)
+			consumed[word] = true
+		}
+	}
+
+	if m := sourcePattern.FindStringSubmatch(query); m != nil {
+		source := strings.TrimPrefix(m[1], "the ")
+		intent.Sources = append(intent.Sources, source)
+		for _, word := range tokenizeQuery(strings.ToLower(source)) {
+			consumed[word] = true
+		}
+	}
+
+	if m := locationPattern.FindStringSubmatch(query); m != nil && !containsFold(intent.Sources, m[1]) {
+		intent.Location = m[1]
+		for _, word := range tokenizeQuery(strings.ToLower(m[1])) {
+			consumed[word] = true
+		}
+	}
+
+	intent.DateFrom, intent.DateTo = extractDateRange(lower, now, consumed)
+
+	if strings.Contains(lower, "important") || strings.Contains(lower, "relevant") {
+		minScore := 0.7
+		intent.MinScore = &minScore
+	}
+
+	for _, word := range tokenizeQuery(lower) {
+		if consumed[word] || fallbackStopWords[word] {
+			continue
+		}
+		intent.Keywords = appendUnique(intent.Keywords, word)
+	}
+}
+
+func extractDateRange(lower string, now time.Time, consumed map[string]bool) (*time.Time, *time.Time) {
+	startOfToday := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	consume := func(words ...string) {
+		for _, w := range words {
+			consumed[w] = true
+		}
+	}
+
+	if m := lastPeriodPattern.FindStringSubmatch(lower); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		unit := time.Hour
+		if strings.HasPrefix(m[2], "day") {
+			unit = 24 * time.Hour
+		}
+		from := now.Add(-time.Duration(n) * unit)
+		consume("last", "past", m[1], m[2])
+		return &from, nil
+	}
+
+	switch {
+	case strings.Contains(lower, "yesterday"):
+		from := startOfToday.Add(-24 * time.Hour)
+		to := startOfToday.Add(-time.Nanosecond)
+		consume("yesterday")
+		return &from, &to
+	case strings.Contains(lower, "today"):
+		consume("today")
+		return &startOfToday, nil
+	case strings.Contains(lower, "this week"), strings.Contains(lower, "last week"), strings.Contains(lower, "past week"):
+		from := now.Add(-7 * 24 * time.Hour)
+		consume("this", "last", "past", "week")
+		return &from, nil
+	}
+
+	return nil, nil
+}
+
+func tokenizeQuery(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func appendUnique(values []string, value string) []string {
+	if containsFold(values, value) {
+		return values
+	}
+	return append(values, value)
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"strings"
+
+	"inshorts-news-api/gazetteer"
+	"inshorts-news-api/models"
+)
+
+// defaultMinScore is used when the query asks for important news without
+// saying how important
+const defaultMinScore = 0.7
+
+// PlanQuery turns an analysed query into a single article filter, so every
+// slot the analysis filled narrows the result instead of only the first.
+// origin is the client's position, nil when the request carried none.
+// Places named in the query are resolved with places, which may be nil.
+func PlanQuery(intent *models.QueryIntent, query string, origin *models.GeoFilter, places *gazetteer.Gazetteer) models.ArticleFilter {
+	filter := models.ArticleFilter{
+		Categories: intent.Categories,
+		Sources:    intent.Sources,
+		MinScore:   intent.MinScore,
+		From:       intent.DateFrom,
+		To:         intent.DateTo,
+	}
+
+	// Answers without slots only name the category or source as an entity
+	switch intent.Intent {
+	case "category":
+		if len(filter.Categories) == 0 && len(intent.Entities) > 0 {
+			filter.Categories = intent.Entities[:1]
+		}
+	case "source":
+		if len(filter.Sources) == 0 && len(intent.Entities) > 0 {
+			filter.Sources = intent.Entities[:1]
+		}
+	case "score":
+		if filter.MinScore == nil {
+			minScore := defaultMinScore
+			filter.MinScore = &minScore
+		}
+	}
+
+	// A place named in the query beats the client's own position. A query
+	// for nearby news without a recognised location may still name a place
+	// the analysis missed.
+	var place *models.GeoFilter
+	if intent.Location != "" {
+		place = ResolveLocation(places, intent.Location)
+	} else if intent.Intent == "nearby" {
+		place = ResolveLocation(places, query)
+	}
+
+	terms := append([]string{}, intent.Keywords...)
+	switch {
+	case place != nil:
+		filter.Near = place
+	case origin != nil && (intent.Intent == "nearby" || intent.Location != ""):
+		filter.Near = origin
+	case intent.Location != "":
+		// Without coordinates the best we can do is look for the place name
+		terms = append(terms, intent.Location)
+	}
+	filter.Text = searchText(terms)
+
+	noFilters := len(filter.Categories) == 0 && len(filter.Sources) == 0 &&
+		filter.MinScore == nil && filter.From == nil && filter.To == nil && filter.Near == nil
+	if filter.Text == "" && noFilters {
+		filter.Text = query
+	}
+
+	switch {
+	case filter.Text != "":
+		filter.Sort = models.SortByTextRank
+	case filter.Near != nil:
+		filter.Sort = models.SortByDistance
+	case intent.Intent == "score":
+		filter.Sort = models.SortByRelevance
+	default:
+		filter.Sort = models.SortByDate
+	}
+
+	return filter
+}
+
+// ResolveLocation finds the place named in text, such as a location
+// parameter, and searches around it. It returns nil for unknown places.
+func ResolveLocation(places *gazetteer.Gazetteer, text string) *models.GeoFilter {
+	place, ok := places.Lookup(text)
+	if !ok {
+		place, ok = places.Find(text)
+	}
+	if !ok {
+		return nil
+	}
+	return &models.GeoFilter{Lat: place.Latitude, Lon: place.Longitude, RadiusKm: place.RadiusKm, Place: place.Name}
+}
+
+// searchText joins terms into a websearch query, quoting multi-word terms
+// so they are matched as phrases
+func searchText(terms []string) string {
+	parts := make([]string, 0, len(terms))
+	for _, term := range terms {
+		term = strings.Trim(strings.TrimSpace(term), `"`)
+		if term == "" {
+			continue
+		}
+		if strings.Contains(term, " ") {
+			term = `"` + term + `"`
+		}
+		parts = append(parts, term)
+	}
+	return strings.Join(parts, " ")
+}
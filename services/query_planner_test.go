@@ -0,0 +1,189 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"inshorts-news-api/gazetteer"
+	"inshorts-news-api/models"
+)
+
+func TestExtractSlots(t *testing.T) {
+	now := time.Date(2024, 3, 15, 14, 30, 0, 0, time.UTC)
+	startOfToday := time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)
+	at := func(t time.Time) *time.Time { return &t }
+	score := func(f float64) *float64 { return &f }
+
+	tests := []struct {
+		query string
+		want  models.QueryIntent
+	}{
+		{
+			query: "latest tech news from Reuters in Mumbai yesterday",
+			want: models.QueryIntent{
+				Categories: []string{"technology"},
+				Sources:    []string{"Reuters"},
+				Location:   "Mumbai",
+				DateFrom:   at(startOfToday.Add(-24 * time.Hour)),
+				DateTo:     at(startOfToday.Add(-time.Nanosecond)),
+			},
+		},
+		{
+			query: "important cricket news in the last 3 days",
+			want: models.QueryIntent{
+				Categories: []string{"cricket"},
+				DateFrom:   at(now.Add(-72 * time.Hour)),
+				MinScore:   score(defaultMinScore),
+			},
+		},
+		{
+			query: "election results today",
+			want:  models.QueryIntent{DateFrom: at(startOfToday), Keywords: []string{"election", "results"}},
+		},
+		{
+			query: "floods near Hazaribagh this week",
+			want: models.QueryIntent{
+				Location: "Hazaribagh",
+				DateFrom: at(now.Add(-7 * 24 * time.Hour)),
+				Keywords: []string{"floods"},
+			},
+		},
+		{
+			query: "relevant stories from the Times of India in the past 12 hours",
+			want: models.QueryIntent{
+				Sources:  []string{"Times of India"},
+				DateFrom: at(now.Add(-12 * time.Hour)),
+				MinScore: score(defaultMinScore),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		var got models.QueryIntent
+		extractSlots(tt.query, &got, now)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("extractSlots(%q) = %+v, want %+v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestPlanQuery(t *testing.T) {
+	places := gazetteer.Bundled()
+	from := time.Date(2024, 3, 14, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 3, 14, 23, 59, 59, 0, time.UTC)
+	minScore := 0.9
+	defaultScore := defaultMinScore
+	origin := &models.GeoFilter{Lat: 12.97, Lon: 77.59, RadiusKm: 10}
+	mumbai := ResolveLocation(places, "Mumbai")
+	hazaribagh := ResolveLocation(places, "Hazaribagh")
+	if mumbai == nil || hazaribagh == nil {
+		t.Fatal("expected the bundled gazetteer to know Mumbai and Hazaribagh")
+	}
+
+	tests := []struct {
+		name   string
+		intent models.QueryIntent
+		query  string
+		origin *models.GeoFilter
+		want   models.ArticleFilter
+	}{
+		{
+			name: "combined slots",
+			intent: models.QueryIntent{
+				Intent:     "search",
+				Categories: []string{"technology"},
+				Sources:    []string{"Reuters"},
+				Location:   "Mumbai",
+				DateFrom:   &from,
+				DateTo:     &to,
+				MinScore:   &minScore,
+				Keywords:   []string{"climate change", "ai"},
+			},
+			query:  "important tech news about climate change and ai from Reuters in Mumbai yesterday",
+			origin: origin,
+			want: models.ArticleFilter{
+				Categories: []string{"technology"},
+				Sources:    []string{"Reuters"},
+				MinScore:   &minScore,
+				From:       &from,
+				To:         &to,
+				Text:       `"climate change" ai`,
+				Near:       mumbai,
+				Sort:       models.SortByTextRank,
+			},
+		},
+		{
+			name:   "nearby with origin",
+			intent: models.QueryIntent{Intent: "nearby"},
+			query:  "news near me",
+			origin: origin,
+			want:   models.ArticleFilter{Near: origin, Sort: models.SortByDistance},
+		},
+		{
+			name:   "nearby without origin",
+			intent: models.QueryIntent{Intent: "nearby"},
+			query:  "news near me",
+			want:   models.ArticleFilter{Text: "news near me", Sort: models.SortByTextRank},
+		},
+		{
+			name:   "nearby naming a place the analysis missed",
+			intent: models.QueryIntent{Intent: "nearby"},
+			query:  "news near hazaribag",
+			origin: origin,
+			want:   models.ArticleFilter{Near: hazaribagh, Sort: models.SortByDistance},
+		},
+		{
+			name:   "unresolved location without origin",
+			intent: models.QueryIntent{Intent: "search", Location: "Atlantis", Keywords: []string{"floods"}},
+			query:  "floods in Atlantis",
+			want:   models.ArticleFilter{Text: "floods Atlantis", Sort: models.SortByTextRank},
+		},
+		{
+			name:   "unresolved location with origin",
+			intent: models.QueryIntent{Intent: "search", Location: "Atlantis"},
+			query:  "news in Atlantis",
+			origin: origin,
+			want:   models.ArticleFilter{Near: origin, Sort: models.SortByDistance},
+		},
+		{
+			name:   "dates only",
+			intent: models.QueryIntent{Intent: "search", DateFrom: &from, DateTo: &to},
+			query:  "news yesterday",
+			want:   models.ArticleFilter{From: &from, To: &to, Sort: models.SortByDate},
+		},
+		{
+			name:   "category from entities",
+			intent: models.QueryIntent{Intent: "category", Entities: []string{"sports", "cricket"}},
+			query:  "sports news",
+			want:   models.ArticleFilter{Categories: []string{"sports"}, Sort: models.SortByDate},
+		},
+		{
+			name:   "source from entities",
+			intent: models.QueryIntent{Intent: "source", Entities: []string{"Reuters"}},
+			query:  "news from Reuters",
+			want:   models.ArticleFilter{Sources: []string{"Reuters"}, Sort: models.SortByDate},
+		},
+		{
+			name:   "score without a threshold",
+			intent: models.QueryIntent{Intent: "score"},
+			query:  "important news",
+			want:   models.ArticleFilter{MinScore: &defaultScore, Sort: models.SortByRelevance},
+		},
+		{
+			name:   "score with a threshold",
+			intent: models.QueryIntent{Intent: "score", MinScore: &minScore},
+			query:  "very important news",
+			want:   models.ArticleFilter{MinScore: &minScore, Sort: models.SortByRelevance},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := PlanQuery(&tt.intent, tt.query, tt.origin, places)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PlanQuery(%q) = %+v, want %+v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"sort"
+
+	"inshorts-news-api/models"
+)
+
+// searchCandidates is how many articles each retriever contributes. Every
+// page is cut from the same candidates, so pagination stays stable.
+const searchCandidates = 200
+
+// Hybrid scores blend cosine similarity, text rank scaled to the best
+// candidate's, and the stored relevance score
+const (
+	hybridSemanticWeight  = 0.6
+	hybridTextWeight      = 0.25
+	hybridRelevanceWeight = 0.15
+)
+
+// SemanticSearch ranks articles by how close their embedding is to the
+// query's
+func (s *ArticleService) SemanticSearch(ctx context.Context, query string, page models.PageRequest) (*models.ArticlePage, error) {
+	matches, err := s.embeddingService.Search(ctx, query, searchCandidates)
+	if err != nil {
+		return nil, err
+	}
+
+	similarity := make(map[string]float64, len(matches))
+	ids := make([]string, len(matches))
+	for i, match := range matches {
+		similarity[match.ID] = match.Score
+		ids[i] = match.ID
+	}
+
+	articles, err := s.repo.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range articles {
+		articles[i].Score = similarity[articles[i].ID]
+	}
+
+	return s.scoredPage(ctx, articles, page)
+}
+
+// HybridSearch merges the semantic and full-text candidates and ranks them
+// on a blend of similarity, text rank and relevance_score
+func (s *ArticleService) HybridSearch(ctx context.Context, query string, page models.PageRequest) (*models.ArticlePage, error) {
+	matches, err := s.embeddingService.Search(ctx, query, searchCandidates)
+	if err != nil {
+		return nil, err
+	}
+
+	textMatches, _, err := s.repo.SearchByText(ctx, query, models.PageRequest{PageSize: searchCandidates})
+	if err != nil {
+		return nil, err
+	}
+
+	similarity := make(map[string]float64, len(matches))
+	ids := make([]string, 0, len(matches))
+	for _, match := range matches {
+		similarity[match.ID] = match.Score
+		ids = append(ids, match.ID)
+	}
+
+	byID := make(map[string]models.Article, len(textMatches)+len(matches))
+	maxRank := 0.0
+	for _, article := range textMatches {
+		byID[article.ID] = article
+		if article.TextRank > maxRank {
+			maxRank = article.TextRank
+		}
+	}
+
+	semanticOnly, err := s.repo.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, article := range semanticOnly {
+		if _, ok := byID[article.ID]; !ok {
+			byID[article.ID] = article
+		}
+	}
+
+	articles := make([]models.Article, 0, len(byID))
+	for _, article := range byID {
+		textScore := 0.0
+		if maxRank > 0 {
+			textScore = article.TextRank / maxRank
+		}
+		article.Score = hybridSemanticWeight*similarity[article.ID] +
+			hybridTextWeight*textScore +
+			hybridRelevanceWeight*article.RelevanceScore
+		articles = append(articles, article)
+	}
+
+	return s.scoredPage(ctx, articles, page)
+}
+
+// scoredPage orders articles by Score and cuts the page after the cursor,
+// mirroring the keyset pagination of the stored listings
+func (s *ArticleService) scoredPage(ctx context.Context, articles []models.Article, page models.PageRequest) (*models.ArticlePage, error) {
+	sort.Slice(articles, func(i, j int) bool {
+		return scoredBefore(articles[i].Score, articles[i].ID, articles[j].Score, articles[j].ID)
+	})
+	if page.Collapse {
+		articles = models.CollapseStories(articles)
+	}
+
+	if page.Cursor != nil {
+		start := sort.Search(len(articles), func(i int) bool {
+			return scoredBefore(page.Cursor.Value, page.Cursor.ID, articles[i].Score, articles[i].ID)
+		})
+		articles = articles[start:]
+	}
+
+	var next *models.Cursor
+	if len(articles) > page.PageSize {
+		articles = articles[:page.PageSize]
+		last := articles[len(articles)-1]
+		next = &models.Cursor{Value: last.Score, ID: last.ID}
+	}
+
+	return s.toPage(ctx, articles, next)
+}
+
+func scoredBefore(scoreA float64, idA string, scoreB float64, idB string) bool {
+	if scoreA != scoreB {
+		return scoreA > scoreB
+	}
+	return idA > idB
+}
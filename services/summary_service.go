@@ -0,0 +1,165 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+
+	"inshorts-news-api/models"
+	"inshorts-news-api/repositories"
+)
+
+// SummaryService serves article summaries from the article_summaries cache
+// and only calls the LLM for articles that are missing or have changed.
+type SummaryService struct {
+	repo       repositories.SummaryStore
+	llmService *LLMService
+}
+
+func NewSummaryService(repo repositories.SummaryStore, llmService *LLMService) *SummaryService {
+	return &SummaryService{
+		repo:       repo,
+		llmService: llmService,
+	}
+}
+
+// GetSummaries returns a summary for every article, keyed by article ID
+func (s *SummaryService) GetSummaries(ctx context.Context, articles []models.Article) (map[string]string, error) {
+	summaries := make(map[string]string, len(articles))
+	if len(articles) == 0 {
+		return summaries, nil
+	}
+
+	// Without a model there is nothing worth caching
+	if !s.llmService.Enabled() {
+		for _, article := range articles {
+			summaries[article.ID] = FallbackSummary(article.Description)
+		}
+		return summaries, nil
+	}
+
+	missing, err := s.loadCached(ctx, articles, summaries)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(missing) > 0 {
+		if err := s.generate(ctx, missing, summaries); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, article := range articles {
+		if _, ok := summaries[article.ID]; !ok {
+			summaries[article.ID] = FallbackSummary(article.Description)
+		}
+	}
+
+	return summaries, nil
+}
+
+// Backfill generates summaries for every stored article that has no fresh
+// cached summary, batchSize articles at a time. It returns how many
+// summaries were generated.
+func (s *SummaryService) Backfill(ctx context.Context, store repositories.ArticleStore, batchSize int) (int, error) {
+	if !s.llmService.Enabled() {
+		return 0, ErrLLMUnavailable
+	}
+
+	generated := 0
+	afterID := ""
+	for {
+		articles, err := store.ListAfterID(ctx, afterID, batchSize)
+		if err != nil {
+			return generated, err
+		}
+		if len(articles) == 0 {
+			return generated, nil
+		}
+		afterID = articles[len(articles)-1].ID
+
+		summaries := make(map[string]string, len(articles))
+		missing, err := s.loadCached(ctx, articles, summaries)
+		if err != nil {
+			return generated, err
+		}
+		if len(missing) == 0 {
+			continue
+		}
+
+		before := len(summaries)
+		if err := s.generate(ctx, missing, summaries); err != nil {
+			return generated, err
+		}
+		generated += len(summaries) - before
+
+		log.Printf("Backfilled %d summaries (up to article %s)", generated, afterID)
+	}
+}
+
+// loadCached fills summaries from the cache and returns the articles whose
+// summary is missing or was generated from different content
+func (s *SummaryService) loadCached(ctx context.Context, articles []models.Article, summaries map[string]string) ([]models.Article, error) {
+	ids := make([]string, len(articles))
+	for i, article := range articles {
+		ids[i] = article.ID
+	}
+
+	cached, err := s.repo.GetByArticleIDs(ctx, ids, s.llmService.Model())
+	if err != nil {
+		return nil, err
+	}
+
+	byArticle := make(map[string]models.ArticleSummary, len(cached))
+	for _, summary := range cached {
+		byArticle[summary.ArticleID] = summary
+	}
+
+	var missing []models.Article
+	for _, article := range articles {
+		summary, ok := byArticle[article.ID]
+		if ok && summary.ContentHash == contentHash(article) {
+			summaries[article.ID] = summary.Summary
+			continue
+		}
+		missing = append(missing, article)
+	}
+
+	return missing, nil
+}
+
+// generate summarises the given articles and stores what the model returned.
+// Summaries finished before ctx was cancelled are still cached.
+func (s *SummaryService) generate(ctx context.Context, articles []models.Article, summaries map[string]string) error {
+	generated, batchErr := s.llmService.BatchGenerateSummaries(ctx, articles)
+
+	model := s.llmService.Model()
+	rows := make([]models.ArticleSummary, 0, len(generated))
+	for _, article := range articles {
+		summary, ok := generated[article.ID]
+		if !ok {
+			continue
+		}
+		summaries[article.ID] = summary
+		rows = append(rows, models.ArticleSummary{
+			ArticleID:   article.ID,
+			Model:       model,
+			ContentHash: contentHash(article),
+			Summary:     summary,
+		})
+	}
+
+	// Keep paid-for summaries even if the request went away meanwhile; a
+	// failed cache write only costs a regeneration next time
+	if err := s.repo.Upsert(context.WithoutCancel(ctx), rows); err != nil {
+		log.Printf("Failed to cache summaries: %v", err)
+	}
+
+	return batchErr
+}
+
+func contentHash(article models.Article) string {
+	sum := sha256.Sum256([]byte(article.Title + "\n" + article.Description))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,63 @@
+package trending
+
+import (
+	"fmt"
+	"math"
+
+	"inshorts-news-api/models"
+)
+
+// CellSize is the width in degrees of the grid cells scores are refreshed
+// in, about 55km north to south
+const CellSize = 0.5
+
+// kmPerDegree is the length of a degree of latitude
+const kmPerDegree = 111.32
+
+// CellOf returns the grid cell holding a point, named "x:y" after its
+// column and row
+func CellOf(lat, lon float64) string {
+	return cellName(int(math.Floor(lon/CellSize)), int(math.Floor(lat/CellSize)))
+}
+
+func cellName(x, y int) string {
+	return fmt.Sprintf("%d:%d", x, y)
+}
+
+// cellBounds returns the area of a cell named by CellOf
+func cellBounds(cell string) (models.BoundingBox, error) {
+	var x, y int
+	if _, err := fmt.Sscanf(cell, "%d:%d", &x, &y); err != nil {
+		return models.BoundingBox{}, fmt.Errorf("invalid trending cell %q", cell)
+	}
+	return models.BoundingBox{
+		MinLat: float64(y) * CellSize,
+		MinLon: float64(x) * CellSize,
+		MaxLat: float64(y+1) * CellSize,
+		MaxLon: float64(x+1) * CellSize,
+	}, nil
+}
+
+// cellsAround lists the cells overlapping the box around a circle, or
+// reports false when there are more than limit of them
+func cellsAround(lat, lon, radiusKm float64, limit int) ([]string, bool) {
+	latDelta := radiusKm / kmPerDegree
+	lonDelta := 180.0
+	if cos := math.Cos(lat * math.Pi / 180); cos > radiusKm/(kmPerDegree*180) {
+		lonDelta = math.Min(radiusKm/(kmPerDegree*cos), 180)
+	}
+
+	minX, maxX := int(math.Floor((lon-lonDelta)/CellSize)), int(math.Floor((lon+lonDelta)/CellSize))
+	minY, maxY := int(math.Floor((lat-latDelta)/CellSize)), int(math.Floor((lat+latDelta)/CellSize))
+	if (maxX-minX+1)*(maxY-minY+1) > limit {
+		return nil, false
+	}
+
+	var cells []string
+	for x := minX; x <= maxX; x++ {
+		for y := minY; y <= maxY; y++ {
+			cells = append(cells, cellName(x, y))
+		}
+	}
+	return cells, true
+}
@@ -0,0 +1,315 @@
+// Package trending keeps precomputed trending scores per grid cell and
+// window, so trending queries need not aggregate user events each time.
+package trending
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"inshorts-news-api/models"
+	"inshorts-news-api/repositories"
+)
+
+// eventBatchSize is the number of events read at a time when looking for
+// cells with new activity
+const eventBatchSize = 1000
+
+// cellBatchSize is the number of cells scored by one query
+const cellBatchSize = 100
+
+// maxLookupCells bounds the cells a lookup may span before the live query
+// is cheaper than checking them all for freshness
+const maxLookupCells = 400
+
+type Options struct {
+	// Interval between refreshes
+	Interval time.Duration
+	// RefreshAfter is how old the scores of a cell may get before they are
+	// recomputed even without new events, as events age out of the window
+	RefreshAfter time.Duration
+	// StaleAfter is how old scores may be and still be served
+	StaleAfter time.Duration
+	// Overlap is how far before the newest event seen each refresh starts
+	// reading, for events stored with an earlier insert time after it was
+	// read, such as a batch that committed late or another instance's clock
+	Overlap time.Duration
+	// Windows are the window lengths, in hours, scores are kept for
+	Windows []int
+	// Trending is how scores are computed. Queries asking for anything
+	// else are not served from the materialized scores.
+	Trending models.TrendingOptions
+}
+
+// Materializer refreshes the trending scores of the grid cells that had new
+// events since its last refresh, of cells whose scores have aged, and of
+// cells lookups found missing. It serves trending queries from those scores
+// while they are fresh.
+type Materializer struct {
+	articles repositories.ArticleStore
+	scores   repositories.TrendingStore
+	options  Options
+	now      func() time.Time
+
+	// refreshMu serializes refreshes; mu guards the fields below it
+	refreshMu   sync.Mutex
+	mu          sync.Mutex
+	watermark   time.Time
+	refreshedAt time.Time
+	missing     map[string]bool
+}
+
+func NewMaterializer(articles repositories.ArticleStore, scores repositories.TrendingStore, options Options) *Materializer {
+	if options.Interval <= 0 {
+		options.Interval = time.Minute
+	}
+	if options.RefreshAfter <= 0 {
+		options.RefreshAfter = 5 * time.Minute
+	}
+	if options.StaleAfter <= 0 {
+		options.StaleAfter = 10 * time.Minute
+	}
+	if options.Overlap <= 0 {
+		options.Overlap = 2 * time.Minute
+	}
+	if len(options.Windows) == 0 {
+		options.Windows = []int{24}
+	}
+	options.Trending = options.Trending.WithDefaults()
+
+	return &Materializer{
+		articles: articles,
+		scores:   scores,
+		options:  options,
+		now:      time.Now,
+		missing:  make(map[string]bool),
+	}
+}
+
+// Run refreshes straight away and then once per interval until ctx is
+// cancelled
+func (m *Materializer) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.options.Interval)
+	defer ticker.Stop()
+
+	for {
+		if cells, err := m.Refresh(ctx); err != nil {
+			log.Printf("Trending refresh failed: %v", err)
+		} else if cells > 0 {
+			log.Printf("Refreshed trending scores of %d cells", cells)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Refresh recomputes the cells with events stored since the last refresh,
+// the cells refreshed longer than RefreshAfter ago and the cells lookups
+// found missing, and returns how many cells it recomputed. The first
+// refresh reads every event that can still count towards a score.
+func (m *Materializer) Refresh(ctx context.Context) (int, error) {
+	m.refreshMu.Lock()
+	defer m.refreshMu.Unlock()
+
+	now := m.now()
+	m.mu.Lock()
+	watermark := m.watermark
+	dirty := m.missing
+	m.missing = make(map[string]bool)
+	m.mu.Unlock()
+
+	var createdAfter time.Time
+	if !watermark.IsZero() {
+		createdAfter = watermark.Add(-m.options.Overlap)
+	}
+	var afterID uint
+
+	// Velocity reads the window before the current one as well
+	longest := time.Duration(slices.Max(m.options.Windows)) * time.Hour
+	since := now.Add(-2 * longest)
+
+	for {
+		events, err := m.articles.ListEventsCreatedAfter(ctx, createdAfter, afterID, since, eventBatchSize)
+		if err != nil {
+			return 0, fmt.Errorf("listing events: %w", err)
+		}
+		if len(events) == 0 {
+			break
+		}
+
+		if err := m.markEventCells(ctx, events, dirty); err != nil {
+			return 0, err
+		}
+		last := events[len(events)-1]
+		createdAfter, afterID = last.CreatedAt, last.ID
+		if last.CreatedAt.After(watermark) {
+			watermark = last.CreatedAt
+		}
+		if len(events) < eventBatchSize {
+			break
+		}
+	}
+
+	aged, err := m.scores.ListCellsRefreshedBefore(ctx, now.Add(-m.options.RefreshAfter))
+	if err != nil {
+		return 0, fmt.Errorf("listing aged cells: %w", err)
+	}
+	for _, cell := range aged {
+		dirty[cell.Cell] = true
+	}
+
+	cells := make([]string, 0, len(dirty))
+	for cell := range dirty {
+		cells = append(cells, cell)
+	}
+	sort.Strings(cells)
+
+	for start := 0; start < len(cells); start += cellBatchSize {
+		batch := cells[start:min(start+cellBatchSize, len(cells))]
+		for _, windowHours := range m.options.Windows {
+			if err := m.refreshCells(ctx, batch, windowHours, now); err != nil {
+				return 0, fmt.Errorf("refreshing cells: %w", err)
+			}
+		}
+	}
+
+	m.mu.Lock()
+	m.watermark = watermark
+	m.refreshedAt = now
+	m.mu.Unlock()
+
+	return len(cells), nil
+}
+
+// markEventCells marks the cells of the articles of events as dirty
+func (m *Materializer) markEventCells(ctx context.Context, events []models.UserEvent, dirty map[string]bool) error {
+	seen := make(map[string]bool)
+	var ids []string
+	for _, event := range events {
+		if !seen[event.ArticleID] {
+			seen[event.ArticleID] = true
+			ids = append(ids, event.ArticleID)
+		}
+	}
+
+	articles, err := m.articles.GetByIDs(ctx, ids)
+	if err != nil {
+		return fmt.Errorf("loading event articles: %w", err)
+	}
+	for _, article := range articles {
+		dirty[CellOf(article.Latitude, article.Longitude)] = true
+	}
+	return nil
+}
+
+// refreshCells scores cells in one pass over their events and replaces the
+// scores of each
+func (m *Materializer) refreshCells(ctx context.Context, cells []string, windowHours int, now time.Time) error {
+	boxes := make([]models.BoundingBox, len(cells))
+	scores := make(map[string][]models.TrendingScore, len(cells))
+	for i, cell := range cells {
+		box, err := cellBounds(cell)
+		if err != nil {
+			return err
+		}
+		boxes[i] = box
+		scores[cell] = nil
+	}
+
+	query := models.TrendingQuery{
+		TrendingOptions: m.options.Trending,
+		Window:          time.Duration(windowHours) * time.Hour,
+	}
+	articles, err := m.articles.ScoreTrendingWithin(ctx, boxes, query)
+	if err != nil {
+		return err
+	}
+
+	for _, article := range articles {
+		// Articles on the edge of a box may belong to a cell outside the batch
+		cell := CellOf(article.Latitude, article.Longitude)
+		if _, ok := scores[cell]; !ok {
+			continue
+		}
+		scores[cell] = append(scores[cell], models.TrendingScore{
+			ArticleID:        article.ID,
+			WindowHours:      windowHours,
+			Algorithm:        query.Algorithm,
+			Cell:             cell,
+			Score:            article.TrendingScore,
+			InteractionCount: article.InteractionCount,
+			RefreshedAt:      now,
+		})
+	}
+
+	for _, cell := range cells {
+		if err := m.scores.ReplaceScores(ctx, cell, windowHours, query.Algorithm, scores[cell], now); err != nil {
+			return fmt.Errorf("replacing scores of cell %s: %w", cell, err)
+		}
+	}
+	return nil
+}
+
+// Lookup serves a trending query from the materialized scores. It reports
+// false when the scores cannot answer it: the query asks for options or a
+// window that are not materialized, spans too many cells, or one of its
+// cells is stale or was never refreshed. Missing cells are refreshed next
+// time. An empty cell is as fresh as the last refresh, which would have
+// scored any events it had since.
+func (m *Materializer) Lookup(ctx context.Context, query models.TrendingQuery) ([]models.TrendingArticle, bool, error) {
+	windowHours := int(query.Window.Hours())
+	if time.Duration(windowHours)*time.Hour != query.Window || !slices.Contains(m.options.Windows, windowHours) {
+		return nil, false, nil
+	}
+	if !reflect.DeepEqual(query.TrendingOptions, m.options.Trending) {
+		return nil, false, nil
+	}
+
+	cells, ok := cellsAround(query.Lat, query.Lon, query.RadiusKm, maxLookupCells)
+	if !ok {
+		return nil, false, nil
+	}
+
+	found, err := m.scores.GetCells(ctx, cells, windowHours, query.Algorithm)
+	if err != nil {
+		return nil, false, err
+	}
+
+	m.mu.Lock()
+	lastRefresh := m.refreshedAt
+	for _, cell := range cells {
+		if _, ok := found[cell]; !ok {
+			m.missing[cell] = true
+		}
+	}
+	m.mu.Unlock()
+	if len(found) < len(cells) {
+		return nil, false, nil
+	}
+
+	staleBefore := m.now().Add(-m.options.StaleAfter)
+	for _, cell := range found {
+		refreshedAt := cell.RefreshedAt
+		if cell.Empty {
+			refreshedAt = lastRefresh
+		}
+		if refreshedAt.Before(staleBefore) {
+			return nil, false, nil
+		}
+	}
+
+	articles, err := m.scores.GetTrending(ctx, query)
+	if err != nil {
+		return nil, false, err
+	}
+	return articles, true, nil
+}
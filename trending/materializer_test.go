@@ -0,0 +1,149 @@
+package trending
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"inshorts-news-api/models"
+	"inshorts-news-api/repositories"
+)
+
+func TestMaterializerRefreshesCellsWithNewEvents(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	articles := repositories.NewMemoryArticleStore()
+	for _, a := range []models.Article{
+		{ID: "mumbai", Title: "Mumbai", Latitude: 19.07, Longitude: 72.87, PublicationDate: now.Add(-time.Hour)},
+		{ID: "pune", Title: "Pune", Latitude: 18.52, Longitude: 73.86, PublicationDate: now.Add(-2 * time.Hour)},
+	} {
+		if err := articles.Create(ctx, &a); err != nil {
+			t.Fatalf("creating article: %v", err)
+		}
+	}
+	record := func(articleID, eventType string, count int) {
+		for i := 0; i < count; i++ {
+			err := articles.CreateUserEvent(ctx, &models.UserEvent{
+				ArticleID: articleID,
+				EventType: eventType,
+				Timestamp: now.Add(-10 * time.Minute),
+			})
+			if err != nil {
+				t.Fatalf("recording event: %v", err)
+			}
+		}
+	}
+	record("mumbai", "click", 3)
+	record("pune", "view", 1)
+
+	// Events are stored moments apart, so only the newest is read again
+	m := NewMaterializer(articles, repositories.NewMemoryTrendingStore(articles), Options{Overlap: time.Nanosecond})
+	m.now = func() time.Time { return now }
+
+	refresh := func(expected int) {
+		t.Helper()
+		cells, err := m.Refresh(ctx)
+		if err != nil {
+			t.Fatalf("refresh failed: %v", err)
+		}
+		if cells != expected {
+			t.Fatalf("expected %d cells refreshed, got %d", expected, cells)
+		}
+	}
+	query := func(lat, lon float64) models.TrendingQuery {
+		return models.TrendingQuery{
+			TrendingOptions: models.TrendingOptions{}.WithDefaults(),
+			Lat:             lat,
+			Lon:             lon,
+			RadiusKm:        10,
+			Limit:           10,
+			Window:          24 * time.Hour,
+		}
+	}
+	lookup := func(q models.TrendingQuery) ([]models.TrendingArticle, bool) {
+		t.Helper()
+		results, ok, err := m.Lookup(ctx, q)
+		if err != nil {
+			t.Fatalf("lookup failed: %v", err)
+		}
+		return results, ok
+	}
+
+	// Lookups of cells that were never refreshed fall back to the live
+	// query, and have those cells refreshed next time
+	if _, ok := lookup(query(19.07, 72.87)); ok {
+		t.Fatal("expected no materialized scores before the first refresh")
+	}
+	if _, ok := lookup(query(18.52, 73.86)); ok {
+		t.Fatal("expected no materialized scores before the first refresh")
+	}
+
+	// The cells of both events, and their empty neighbours
+	refresh(4)
+	results, ok := lookup(query(19.07, 72.87))
+	if !ok || len(results) != 1 || results[0].ID != "mumbai" || results[0].InteractionCount != 3 {
+		t.Fatalf("expected the Mumbai article with 3 interactions, got %v %+v", ok, results)
+	}
+	live, err := articles.GetTrendingByLocation(ctx, query(19.07, 72.87))
+	if err != nil {
+		t.Fatalf("live query failed: %v", err)
+	}
+	if diff := live[0].TrendingScore - results[0].TrendingScore; diff > 1 || diff < -1 {
+		t.Fatalf("materialized score %v differs from live score %v", results[0].TrendingScore, live[0].TrendingScore)
+	}
+
+	// New events only refresh the cell they happened in
+	record("pune", "view", 5)
+	if results, _ := lookup(query(18.52, 73.86)); len(results) != 1 || results[0].InteractionCount != 1 {
+		t.Fatalf("expected the previous Pune score until the next refresh, got %+v", results)
+	}
+	refresh(1)
+	if results, _ := lookup(query(18.52, 73.86)); len(results) != 1 || results[0].InteractionCount != 6 {
+		t.Fatalf("expected the Pune article with 6 interactions, got %+v", results)
+	}
+
+	// Queries that were not materialized fall back to the live query
+	velocity := query(19.07, 72.87)
+	velocity.Algorithm = models.TrendingVelocity
+	shorter := query(19.07, 72.87)
+	shorter.Window = 6 * time.Hour
+	wide := query(19.07, 72.87)
+	wide.RadiusKm = 5000
+	for name, q := range map[string]models.TrendingQuery{"velocity": velocity, "shorter window": shorter, "wide radius": wide} {
+		if _, ok := lookup(q); ok {
+			t.Errorf("%s: expected a fallback to the live query", name)
+		}
+	}
+
+	// So do cells that have not been refreshed lately
+	m.now = func() time.Time { return now.Add(11 * time.Minute) }
+	if _, ok := lookup(query(19.07, 72.87)); ok {
+		t.Fatal("expected stale scores to fall back to the live query")
+	}
+
+	// Aged cells are refreshed even without new events; empty ones have
+	// nothing to age out
+	refresh(2)
+	if _, ok := lookup(query(19.07, 72.87)); !ok {
+		t.Fatal("expected refreshed scores to be served")
+	}
+}
+
+func TestCellsAround(t *testing.T) {
+	if cell := CellOf(19.07, 72.87); cell != "145:38" {
+		t.Fatalf("expected cell 145:38, got %s", cell)
+	}
+	box, err := cellBounds("145:38")
+	if err != nil || !box.Contains(19.07, 72.87) {
+		t.Fatalf("expected the cell bounds to hold its point, got %+v %v", box, err)
+	}
+
+	cells, ok := cellsAround(19.07, 72.87, 5, maxLookupCells)
+	if !ok || len(cells) != 1 || cells[0] != "145:38" {
+		t.Fatalf("expected only cell 145:38, got %v", cells)
+	}
+	if _, ok := cellsAround(19.07, 72.87, 2000, maxLookupCells); ok {
+		t.Fatal("expected a 2000km radius to span too many cells")
+	}
+}
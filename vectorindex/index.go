@@ -0,0 +1,280 @@
+// Package vectorindex is an in-process nearest-neighbour index over
+// embeddings, used when the database cannot search vectors itself.
+package vectorindex
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+// Match is an indexed vector and its cosine similarity to the query
+type Match struct {
+	ID    string
+	Score float64
+}
+
+type Options struct {
+	// Tables and Bits shape the random-projection hash: each table buckets
+	// vectors by which side of Bits random hyperplanes they fall on
+	Tables int
+	Bits   int
+	// ExactBelow is the size under which searches scan every vector, which
+	// is both exact and fast enough for small indexes
+	ExactBelow int
+}
+
+// Index finds approximate nearest neighbours by cosine similarity with
+// locality-sensitive hashing. Candidates from the query's buckets, and the
+// buckets one bit away, are ranked exactly. It is safe for concurrent use.
+type Index struct {
+	mu      sync.RWMutex
+	options Options
+	dims    int
+
+	planes  [][][]float32 // table, bit, dimension
+	buckets []map[uint64][]int
+
+	ids     []string
+	vectors [][]float32 // unit length; nil while free
+	keys    [][]uint64  // bucket key in each table
+	slots   map[string]int
+	free    []int // removed slots, reused by Add
+}
+
+func New(options Options) *Index {
+	if options.Tables <= 0 {
+		options.Tables = 8
+	}
+	if options.Bits <= 0 || options.Bits > 64 {
+		options.Bits = 12
+	}
+	if options.ExactBelow <= 0 {
+		options.ExactBelow = 5000
+	}
+
+	buckets := make([]map[uint64][]int, options.Tables)
+	for i := range buckets {
+		buckets[i] = make(map[uint64][]int)
+	}
+
+	return &Index{
+		options: options,
+		buckets: buckets,
+		slots:   make(map[string]int),
+	}
+}
+
+// Len returns the number of indexed vectors
+func (ix *Index) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return len(ix.slots)
+}
+
+// Add indexes vector under id, replacing any earlier vector for id. All
+// vectors must have the dimension of the first one.
+func (ix *Index) Add(id string, vector []float32) error {
+	unit, ok := normalize(vector)
+	if !ok {
+		return fmt.Errorf("vector for %s is empty or zero", id)
+	}
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	if ix.dims == 0 {
+		ix.dims = len(unit)
+		ix.planes = randomPlanes(ix.options, ix.dims)
+	}
+	if len(unit) != ix.dims {
+		return fmt.Errorf("vector for %s has %d dimensions, index has %d", id, len(unit), ix.dims)
+	}
+
+	ix.remove(id)
+
+	keys := make([]uint64, len(ix.buckets))
+	for t := range keys {
+		keys[t] = ix.hash(t, unit)
+	}
+
+	var slot int
+	if n := len(ix.free); n > 0 {
+		slot = ix.free[n-1]
+		ix.free = ix.free[:n-1]
+		ix.ids[slot], ix.vectors[slot], ix.keys[slot] = id, unit, keys
+	} else {
+		slot = len(ix.vectors)
+		ix.ids = append(ix.ids, id)
+		ix.vectors = append(ix.vectors, unit)
+		ix.keys = append(ix.keys, keys)
+	}
+	ix.slots[id] = slot
+	for t, key := range keys {
+		ix.buckets[t][key] = append(ix.buckets[t][key], slot)
+	}
+	return nil
+}
+
+// Remove drops id from the index
+func (ix *Index) Remove(id string) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.remove(id)
+}
+
+// remove takes the slot out of its buckets and frees it for the next Add
+func (ix *Index) remove(id string) {
+	slot, ok := ix.slots[id]
+	if !ok {
+		return
+	}
+
+	for t, key := range ix.keys[slot] {
+		bucket := ix.buckets[t][key]
+		for i, s := range bucket {
+			if s == slot {
+				bucket[i] = bucket[len(bucket)-1]
+				bucket = bucket[:len(bucket)-1]
+				break
+			}
+		}
+		if len(bucket) == 0 {
+			delete(ix.buckets[t], key)
+		} else {
+			ix.buckets[t][key] = bucket
+		}
+	}
+
+	ix.ids[slot], ix.vectors[slot], ix.keys[slot] = "", nil, nil
+	delete(ix.slots, id)
+	ix.free = append(ix.free, slot)
+}
+
+// Search returns up to k vectors most similar to query, best first
+func (ix *Index) Search(query []float32, k int) []Match {
+	unit, ok := normalize(query)
+	if !ok || k <= 0 {
+		return nil
+	}
+
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	if len(unit) != ix.dims {
+		return nil
+	}
+
+	var matches []Match
+	if len(ix.slots) < ix.options.ExactBelow {
+		matches = ix.rank(unit, ix.all)
+	} else {
+		matches = ix.rank(unit, func(yield func(int)) {
+			for t := range ix.buckets {
+				key := ix.hash(t, unit)
+				for _, slot := range ix.buckets[t][key] {
+					yield(slot)
+				}
+				for bit := 0; bit < ix.options.Bits; bit++ {
+					for _, slot := range ix.buckets[t][key^(1<<uint(bit))] {
+						yield(slot)
+					}
+				}
+			}
+		})
+		// Too few candidates near the query: fall back to a full scan
+		if len(matches) < k {
+			matches = ix.rank(unit, ix.all)
+		}
+	}
+
+	if len(matches) > k {
+		matches = matches[:k]
+	}
+	return matches
+}
+
+// all produces every live slot, skipping removed ones still awaiting reuse
+func (ix *Index) all(yield func(int)) {
+	for _, slot := range ix.slots {
+		yield(slot)
+	}
+}
+
+// rank scores each distinct slot produced by candidates
+func (ix *Index) rank(query []float32, candidates func(yield func(int))) []Match {
+	seen := make(map[int]bool)
+	var matches []Match
+
+	candidates(func(slot int) {
+		if seen[slot] {
+			return
+		}
+		seen[slot] = true
+		matches = append(matches, Match{ID: ix.ids[slot], Score: dot(query, ix.vectors[slot])})
+	})
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].ID < matches[j].ID
+	})
+	return matches
+}
+
+func (ix *Index) hash(table int, vector []float32) uint64 {
+	var key uint64
+	for bit, plane := range ix.planes[table] {
+		if dot(plane, vector) >= 0 {
+			key |= 1 << uint(bit)
+		}
+	}
+	return key
+}
+
+// randomPlanes uses a fixed seed so an index rebuilt from the same vectors
+// buckets them the same way
+func randomPlanes(options Options, dims int) [][][]float32 {
+	rng := rand.New(rand.NewSource(1))
+
+	planes := make([][][]float32, options.Tables)
+	for t := range planes {
+		planes[t] = make([][]float32, options.Bits)
+		for b := range planes[t] {
+			plane := make([]float32, dims)
+			for d := range plane {
+				plane[d] = float32(rng.NormFloat64())
+			}
+			planes[t][b] = plane
+		}
+	}
+	return planes
+}
+
+func normalize(vector []float32) ([]float32, bool) {
+	var norm float64
+	for _, v := range vector {
+		norm += float64(v) * float64(v)
+	}
+	if norm == 0 {
+		return nil, false
+	}
+
+	norm = math.Sqrt(norm)
+	unit := make([]float32, len(vector))
+	for i, v := range vector {
+		unit[i] = float32(float64(v) / norm)
+	}
+	return unit, true
+}
+
+func dot(a, b []float32) float64 {
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
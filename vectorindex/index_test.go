@@ -0,0 +1,138 @@
+package vectorindex_test
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"inshorts-news-api/vectorindex"
+)
+
+func randomVector(rng *rand.Rand, dims int) []float32 {
+	vector := make([]float32, dims)
+	for i := range vector {
+		vector[i] = float32(rng.NormFloat64())
+	}
+	return vector
+}
+
+func TestSearchFindsNearestNeighbours(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+
+	// ExactBelow 1 forces the hashed path even for this small index
+	for _, options := range []vectorindex.Options{{}, {ExactBelow: 1}} {
+		index := vectorindex.New(options)
+		vectors := make([][]float32, 500)
+		for i := range vectors {
+			vectors[i] = randomVector(rng, 32)
+			if err := index.Add(fmt.Sprintf("v%d", i), vectors[i]); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// A slightly perturbed copy of a vector finds the original first
+		query := append([]float32(nil), vectors[42]...)
+		for i := range query {
+			query[i] += float32(rng.NormFloat64() * 0.05)
+		}
+		matches := index.Search(query, 5)
+		want := "v42"
+		if len(matches) != 5 || matches[0].ID != want {
+			t.Fatalf("options %+v: expected %s first, got %+v", options, want, matches)
+		}
+		for i := 1; i < len(matches); i++ {
+			if matches[i].Score > matches[i-1].Score {
+				t.Fatalf("matches not sorted: %+v", matches)
+			}
+		}
+	}
+}
+
+func TestAddReplacesAndRemoveDrops(t *testing.T) {
+	index := vectorindex.New(vectorindex.Options{})
+
+	if err := index.Add("a", []float32{1, 0, 0}); err != nil {
+		t.Fatal(err)
+	}
+	if err := index.Add("b", []float32{0, 1, 0}); err != nil {
+		t.Fatal(err)
+	}
+	if err := index.Add("a", []float32{0, 0, 1}); err != nil {
+		t.Fatal(err)
+	}
+	if index.Len() != 2 {
+		t.Fatalf("expected 2 vectors, got %d", index.Len())
+	}
+
+	if matches := index.Search([]float32{0, 0, 2}, 1); len(matches) != 1 || matches[0].ID != "a" || matches[0].Score < 0.999 {
+		t.Fatalf("expected the replaced vector of a, got %+v", matches)
+	}
+
+	index.Remove("a")
+	if matches := index.Search([]float32{0, 0, 1}, 2); len(matches) != 1 || matches[0].ID != "b" {
+		t.Fatalf("expected only b after removal, got %+v", matches)
+	}
+
+	// Freed slots are reused rather than scanned or bucketed again
+	for i := 0; i < 100; i++ {
+		if err := index.Add("c", []float32{float32(i), 1, 1}); err != nil {
+			t.Fatal(err)
+		}
+		index.Remove("c")
+	}
+	if err := index.Add("c", []float32{1, 1, 0}); err != nil {
+		t.Fatal(err)
+	}
+	if matches := index.Search([]float32{1, 1, 0}, 5); len(matches) != 2 || matches[0].ID != "c" {
+		t.Fatalf("expected c then b, got %+v", matches)
+	}
+
+	if err := index.Add("c", []float32{1, 0}); err == nil {
+		t.Error("expected a dimension mismatch error")
+	}
+	if err := index.Add("d", []float32{0, 0, 0}); err == nil {
+		t.Error("expected an error for a zero vector")
+	}
+}
+
+func TestRemoveKeepsHashedSearchAccurate(t *testing.T) {
+	rng := rand.New(rand.NewSource(11))
+	index := vectorindex.New(vectorindex.Options{ExactBelow: 1})
+
+	vectors := make([][]float32, 300)
+	for i := range vectors {
+		vectors[i] = randomVector(rng, 16)
+		if err := index.Add(fmt.Sprintf("v%d", i), vectors[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	removed := make(map[string]bool)
+	for i := 0; i < 200; i++ {
+		removed[fmt.Sprintf("v%d", i)] = true
+		index.Remove(fmt.Sprintf("v%d", i))
+	}
+	for i := 0; i < 50; i++ {
+		vectors[i] = randomVector(rng, 16)
+		if err := index.Add(fmt.Sprintf("w%d", i), vectors[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if index.Len() != 150 {
+		t.Fatalf("expected 150 vectors, got %d", index.Len())
+	}
+	for _, probe := range []struct {
+		id     string
+		vector []float32
+	}{{"w7", vectors[7]}, {"v250", vectors[250]}} {
+		matches := index.Search(probe.vector, 3)
+		if len(matches) != 3 || matches[0].ID != probe.id {
+			t.Fatalf("expected %s first, got %+v", probe.id, matches)
+		}
+		for _, match := range matches {
+			if removed[match.ID] {
+				t.Fatalf("removed vector %s returned", match.ID)
+			}
+		}
+	}
+}